@@ -2,7 +2,9 @@ module github.com/AgustinPagotto/go-webcrawler
 
 go 1.24.5
 
+require golang.org/x/net v0.42.0
+
 require (
-	github.com/mattn/go-sqlite3 v1.14.30 // indirect
-	golang.org/x/net v0.42.0 // indirect
+	github.com/mattn/go-sqlite3 v1.14.30
+	golang.org/x/text v0.27.0 // indirect
 )
@@ -0,0 +1,286 @@
+package crawl
+
+import (
+	"net/netip"
+	"net/url"
+	"runtime"
+	"time"
+
+	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
+)
+
+const DefaultUserAgent = "go-webcrawler"
+
+const defaultCrawlTimeout = 5 * time.Minute
+
+type Hooks struct {
+	OnPage func(Result)
+	OnSkip func(url string, reason string)
+}
+
+type Config struct {
+	MaxDepth     int
+	MaxPages     int
+	CrawlTimeout time.Duration
+	FetchTimeout time.Duration
+	Workers      int
+	MaxRedirects int
+	Retry        RetryPolicy
+	UserAgent    string
+	MaxPerHost   int
+	HostDelay    time.Duration
+	SchemePolicy validate.SchemePolicy
+	Scope        func(u *url.URL) bool
+	ScopeRules   ScopeRules
+	// TrackingParams are the query parameters removed from urls before they
+	// are compared, an entry ending in "*" matches a prefix. Empty keeps
+	// every parameter.
+	TrackingParams []string
+	Fetcher        Fetcher
+	// Validators makes every request conditional on the page having
+	// changed since the crawl that saved them.
+	Validators ValidatorLookup
+	// StoredLinks gives the links of a page that didn't change, so the
+	// crawl keeps going below it without downloading it.
+	StoredLinks LinkLookup
+	// MaxBodySize is the most bytes of a page parsed, the rest is ignored.
+	MaxBodySize int64
+	// HeadUnknown sends a HEAD request before downloading urls whose
+	// extension doesn't look like a page, so big files are never fetched.
+	HeadUnknown bool
+	// SkipNofollow stops the crawl from following links with rel=nofollow,
+	// they are still recorded on the page they were found.
+	SkipNofollow bool
+	// IgnoreRobotsMeta crawls and indexes pages marked noindex or nofollow
+	// by their robots meta tag or X-Robots-Tag header, for audits.
+	IgnoreRobotsMeta bool
+	// CheckAssets requests every asset a page loads to record its status.
+	CheckAssets bool
+	// AllowedNetworks are private or reserved networks the default fetcher
+	// may still connect to, every other internal address is refused.
+	AllowedNetworks []netip.Prefix
+	Hooks           Hooks
+	StreamOnly      bool
+}
+
+type Option func(*Config)
+
+func DefaultConfig() Config {
+	return Config{
+		MaxDepth:       1,
+		CrawlTimeout:   defaultCrawlTimeout,
+		FetchTimeout:   defaultFetchTimeout,
+		Workers:        runtime.NumCPU(),
+		MaxRedirects:   defaultMaxRedirects,
+		Retry:          DefaultRetryPolicy(),
+		MaxBodySize:    defaultMaxBodySize,
+		UserAgent:      DefaultUserAgent,
+		MaxPerHost:     defaultMaxPerHost,
+		HostDelay:      defaultHostDelay,
+		TrackingParams: validate.DefaultTrackingParams(),
+	}
+}
+
+func WithConfig(config Config) Option {
+	return func(c *Config) {
+		*c = config
+	}
+}
+
+func WithMaxDepth(depth int) Option {
+	return func(c *Config) {
+		c.MaxDepth = depth
+	}
+}
+
+// WithMaxPages caps the number of pages fetched in a crawl, 0 means no limit.
+func WithMaxPages(pages int) Option {
+	return func(c *Config) {
+		c.MaxPages = pages
+	}
+}
+
+func WithCrawlTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.CrawlTimeout = timeout
+	}
+}
+
+func WithFetchTimeout(timeout time.Duration) Option {
+	return func(c *Config) {
+		c.FetchTimeout = timeout
+	}
+}
+
+func WithWorkers(workers int) Option {
+	return func(c *Config) {
+		c.Workers = workers
+	}
+}
+
+func WithMaxRedirects(redirects int) Option {
+	return func(c *Config) {
+		c.MaxRedirects = redirects
+	}
+}
+
+// WithRetryPolicy sets how transient failures are retried, a MaxAttempts of
+// 1 disables retries.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Config) {
+		c.Retry = policy
+	}
+}
+
+func WithUserAgent(userAgent string) Option {
+	return func(c *Config) {
+		c.UserAgent = userAgent
+	}
+}
+
+func WithHostLimits(maxPerHost int, delay time.Duration) Option {
+	return func(c *Config) {
+		c.MaxPerHost = maxPerHost
+		c.HostDelay = delay
+	}
+}
+
+func WithSchemePolicy(policy validate.SchemePolicy) Option {
+	return func(c *Config) {
+		c.SchemePolicy = policy
+	}
+}
+
+func WithScope(scope func(u *url.URL) bool) Option {
+	return func(c *Config) {
+		c.Scope = scope
+	}
+}
+
+func WithScopeRules(rules ScopeRules) Option {
+	return func(c *Config) {
+		c.ScopeRules = rules
+	}
+}
+
+func WithTrackingParams(params []string) Option {
+	return func(c *Config) {
+		c.TrackingParams = params
+	}
+}
+
+func WithValidators(lookup ValidatorLookup) Option {
+	return func(c *Config) {
+		c.Validators = lookup
+	}
+}
+
+func WithStoredLinks(lookup LinkLookup) Option {
+	return func(c *Config) {
+		c.StoredLinks = lookup
+	}
+}
+
+func WithMaxBodySize(size int64) Option {
+	return func(c *Config) {
+		c.MaxBodySize = size
+	}
+}
+
+func WithHeadUnknown() Option {
+	return func(c *Config) {
+		c.HeadUnknown = true
+	}
+}
+
+func WithSkipNofollow() Option {
+	return func(c *Config) {
+		c.SkipNofollow = true
+	}
+}
+
+func WithIgnoreRobotsMeta() Option {
+	return func(c *Config) {
+		c.IgnoreRobotsMeta = true
+	}
+}
+
+func WithCheckAssets() Option {
+	return func(c *Config) {
+		c.CheckAssets = true
+	}
+}
+
+func WithFetcher(fetcher Fetcher) Option {
+	return func(c *Config) {
+		c.Fetcher = fetcher
+	}
+}
+
+func WithAllowedNetworks(networks []netip.Prefix) Option {
+	return func(c *Config) {
+		c.AllowedNetworks = networks
+	}
+}
+
+func WithOnPage(onPage func(Result)) Option {
+	return func(c *Config) {
+		c.Hooks.OnPage = onPage
+	}
+}
+
+func WithOnSkip(onSkip func(url string, reason string)) Option {
+	return func(c *Config) {
+		c.Hooks.OnSkip = onSkip
+	}
+}
+
+// WithStreamOnly stops the crawler from keeping every page and link in
+// memory, they are only delivered through Stream and the OnPage hook.
+func WithStreamOnly() Option {
+	return func(c *Config) {
+		c.StreamOnly = true
+	}
+}
+
+func (c *Config) applyDefaults() {
+	defaults := DefaultConfig()
+	if c.CrawlTimeout <= 0 {
+		c.CrawlTimeout = defaults.CrawlTimeout
+	}
+	if c.FetchTimeout <= 0 {
+		c.FetchTimeout = defaults.FetchTimeout
+	}
+	if c.Workers < 1 {
+		c.Workers = defaults.Workers
+	}
+	if c.MaxRedirects < 0 {
+		c.MaxRedirects = 0
+	}
+	if c.Retry.MaxAttempts < 1 {
+		c.Retry.MaxAttempts = 1
+	}
+	if c.Retry.BaseDelay < 0 {
+		c.Retry.BaseDelay = 0
+	}
+	if c.Retry.MaxDelay < c.Retry.BaseDelay {
+		c.Retry.MaxDelay = c.Retry.BaseDelay
+	}
+	if c.MaxBodySize <= 0 {
+		c.MaxBodySize = defaults.MaxBodySize
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = defaults.UserAgent
+	}
+	if c.MaxPerHost < 1 {
+		c.MaxPerHost = defaults.MaxPerHost
+	}
+	if c.HostDelay < 0 {
+		c.HostDelay = 0
+	}
+	if c.Fetcher == nil && len(c.AllowedNetworks) > 0 {
+		c.Fetcher = NewHTTPFetcher(c.FetchTimeout, NewTransport(NewIPGuard(c.AllowedNetworks)))
+	} else if c.Fetcher == nil {
+		c.Fetcher = NewHTTPFetcher(c.FetchTimeout, sharedTransport)
+	}
+}
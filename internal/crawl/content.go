@@ -0,0 +1,252 @@
+package crawl
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"golang.org/x/net/html/charset"
+)
+
+const defaultMaxBodySize = 10 << 20
+
+const (
+	AssetImage      = "image"
+	AssetStylesheet = "stylesheet"
+	AssetScript     = "script"
+	AssetFont       = "font"
+	AssetIcon       = "icon"
+	AssetFrame      = "iframe"
+	AssetVideo      = "video"
+	AssetAudio      = "audio"
+	AssetDocument   = "document"
+	AssetData       = "data"
+	AssetOther      = "other"
+)
+
+// Asset is a resource that isn't an html page, it is recorded with its type
+// instead of being parsed for links.
+type Asset struct {
+	// Source is the page the asset was found on, empty when the asset was
+	// crawled as a link.
+	Source      string
+	URL         string
+	Kind        string
+	ContentType string
+	Size        int64
+	Status      int
+	// Unchecked is why the asset was never requested, like a scheme the
+	// policy refuses or a robots.txt rule. Its Status is 0 but it isn't
+	// broken.
+	Unchecked string
+	// href is the resolved url the asset is requested with, URL is its
+	// normalized form.
+	href string
+}
+
+var htmlExtensions = map[string]bool{
+	"": true, ".html": true, ".htm": true, ".xhtml": true, ".shtml": true,
+	".php": true, ".asp": true, ".aspx": true, ".jsp": true, ".cgi": true,
+}
+
+// knownHTMLExtension reports if the path looks like a page, urls with any
+// other extension can be checked with a HEAD request first.
+func knownHTMLExtension(urlPath string) bool {
+	return htmlExtensions[strings.ToLower(path.Ext(urlPath))]
+}
+
+func mediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(contentType))
+	}
+	return mediaType
+}
+
+func isHTML(contentType string) bool {
+	switch mediaType(contentType) {
+	case "text/html", "application/xhtml+xml":
+		return true
+	}
+	return false
+}
+
+// sniffContentType returns the content type of resp, detected from the first
+// bytes of the body when the server didn't send one. The returned reader must
+// be used instead of the body.
+func sniffContentType(resp *http.Response) (string, io.Reader) {
+	if contentType := resp.Header.Get("Content-Type"); contentType != "" {
+		return contentType, resp.Body
+	}
+	reader := bufio.NewReaderSize(resp.Body, 512)
+	head, _ := reader.Peek(512)
+	return http.DetectContentType(head), reader
+}
+
+func assetKind(contentType string) string {
+	mediaType := mediaType(contentType)
+	switch {
+	case strings.HasPrefix(mediaType, "image/"):
+		return AssetImage
+	case strings.HasPrefix(mediaType, "video/"):
+		return AssetVideo
+	case strings.HasPrefix(mediaType, "audio/"):
+		return AssetAudio
+	case strings.HasPrefix(mediaType, "font/"), strings.Contains(mediaType, "font"):
+		return AssetFont
+	case mediaType == "text/css":
+		return AssetStylesheet
+	case strings.Contains(mediaType, "javascript"), strings.Contains(mediaType, "ecmascript"):
+		return AssetScript
+	case mediaType == "application/pdf", strings.HasPrefix(mediaType, "application/msword"),
+		strings.HasPrefix(mediaType, "application/vnd."), mediaType == "text/plain":
+		return AssetDocument
+	case strings.Contains(mediaType, "json"), strings.Contains(mediaType, "xml"), mediaType == "text/csv":
+		return AssetData
+	}
+	return AssetOther
+}
+
+func newAsset(u string, resp *http.Response, contentType string) *Asset {
+	asset := &Asset{URL: u, Kind: assetKind(contentType), ContentType: mediaType(contentType), Status: resp.StatusCode}
+	if resp.ContentLength > 0 {
+		asset.Size = resp.ContentLength
+	}
+	return asset
+}
+
+// limitedBody stops reading after limit bytes and remembers if the body had
+// more than that.
+type limitedBody struct {
+	r         io.Reader
+	remaining int64
+	truncated bool
+}
+
+func (l *limitedBody) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		var probe [1]byte
+		if n, _ := l.r.Read(probe[:]); n > 0 {
+			l.truncated = true
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
+
+// utf8Body decodes body to utf-8, the charset comes from a BOM, the charset
+// of contentType or a <meta> tag in the first 1024 bytes, in that order, and
+// falls back to windows-1252 when the body isn't valid utf-8. It returns the
+// name of the charset found.
+func utf8Body(body io.Reader, contentType string) (io.Reader, string) {
+	raw := bufio.NewReaderSize(body, 1024)
+	head, _ := raw.Peek(1024)
+	encoding, name, _ := charset.DetermineEncoding(head, contentType)
+	decoded := bufio.NewReader(encoding.NewDecoder().Reader(raw))
+	if bom, _, err := decoded.ReadRune(); err == nil && bom != '\uFEFF' {
+		decoded.UnreadRune()
+	}
+	return decoded, name
+}
+
+// srcsetURLs returns the url of every candidate of a srcset attribute, like
+// "small.png 1x, large.png 2x".
+func srcsetURLs(srcset string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// linkAssetKind returns the kind of the resource a <link> loads, or "" when
+// its rel doesn't load one.
+func linkAssetKind(rel []string, as string) string {
+	for _, value := range rel {
+		switch value {
+		case "stylesheet":
+			return AssetStylesheet
+		case "icon", "apple-touch-icon", "mask-icon":
+			return AssetIcon
+		case "modulepreload":
+			return AssetScript
+		case "preload":
+			switch strings.ToLower(as) {
+			case "script", "worker":
+				return AssetScript
+			case "style":
+				return AssetStylesheet
+			case "font":
+				return AssetFont
+			case "image":
+				return AssetImage
+			case "video":
+				return AssetVideo
+			case "audio", "track":
+				return AssetAudio
+			case "fetch":
+				return AssetData
+			case "document":
+				return AssetFrame
+			}
+			return AssetOther
+		}
+	}
+	return ""
+}
+
+// sourceKind returns the kind of a <source> from the element it belongs to.
+func sourceKind(parent string) string {
+	switch parent {
+	case "video":
+		return AssetVideo
+	case "audio":
+		return AssetAudio
+	case "picture":
+		return AssetImage
+	}
+	return AssetOther
+}
+
+// requestURL returns the url an asset is checked with.
+func (a Asset) requestURL() string {
+	if a.href != "" {
+		return a.href
+	}
+	return a.URL
+}
+
+// assetCache keeps the assets checked during a crawl, pages often share
+// their stylesheets and scripts.
+type assetCache struct {
+	mu     sync.Mutex
+	assets map[string]Asset
+}
+
+func newAssetCache() *assetCache {
+	return &assetCache{assets: make(map[string]Asset)}
+}
+
+func (ac *assetCache) get(url string) (Asset, bool) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	asset, ok := ac.assets[url]
+	return asset, ok
+}
+
+func (ac *assetCache) set(url string, asset Asset) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.assets[url] = asset
+}
@@ -0,0 +1,178 @@
+package crawl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestCrawlContentGuards(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string][]string)
+	site := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requests[req.URL.Path] = append(requests[req.URL.Path], req.Method)
+		mu.Unlock()
+		header := http.Header{}
+		body := ""
+		switch req.URL.Path {
+		case "/":
+			header.Set("Content-Type", "text/html")
+			body = `<a href="/file.pdf">PDF</a><a href="/data">Data</a><a href="/big">Big</a><a href="/image.png">Image</a>`
+		case "/file.pdf":
+			header.Set("Content-Type", "application/pdf")
+			header.Set("Content-Length", "2000000000")
+			body = "%PDF-1.4"
+		case "/data":
+			body = "\x89PNG\r\n\x1a\n"
+		case "/big":
+			body = `<a href="/first">First</a>` + strings.Repeat(" ", 200) + `<a href="/second">Second</a>`
+		case "/image.png":
+			header.Set("Content-Type", "text/html")
+			body = `<a href="/from-image">Not an image</a>`
+		}
+		if req.Method == http.MethodHead {
+			body = ""
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: header, ContentLength: -1, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(site), WithHostLimits(2, 0), WithMaxBodySize(150), WithHeadUnknown())
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	pages := make(map[string]Result)
+	for _, page := range crawler.Pages {
+		pages[page.URL] = page
+	}
+	if pdf := pages["https://example.com/file.pdf"]; pdf.Asset == nil || pdf.Asset.Kind != AssetDocument {
+		t.Errorf("Expected the pdf to be recorded as a document, got %+v", pdf.Asset)
+	}
+	if methods := requests["/file.pdf"]; len(methods) != 1 || methods[0] != http.MethodHead {
+		t.Errorf("Expected the pdf to only get a HEAD request, got %v", methods)
+	}
+	if data := pages["https://example.com/data"]; data.Asset == nil || data.Asset.Kind != AssetImage || len(data.InfoCrawled) != 0 {
+		t.Errorf("Expected a body without content type to be sniffed, got %+v", data)
+	}
+	if big := pages["https://example.com/big"]; !big.Truncated || len(big.InfoCrawled) != 1 {
+		t.Errorf("Expected only the beginning of a big page to be parsed, got %+v", big)
+	}
+	if image := pages["https://example.com/image.png"]; image.Asset != nil || len(image.InfoCrawled) != 1 {
+		t.Errorf("Expected an unknown extension serving html to be parsed, got %+v", image)
+	}
+}
+
+func TestUtf8Body(t *testing.T) {
+	testCases := []struct {
+		name        string
+		body        string
+		contentType string
+		expected    string
+		charset     string
+	}{
+		{name: "Header charset", body: "<a>caf\xe9</a>", contentType: "text/html; charset=ISO-8859-1", expected: "<a>café</a>", charset: "windows-1252"},
+		{name: "Meta charset", body: `<meta charset="shift_jis"><a>` + "\x93\xfa\x96\x7b" + `</a>`, contentType: "text/html", expected: `<meta charset="shift_jis"><a>日本</a>`, charset: "shift_jis"},
+		{name: "Http-equiv charset", body: `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><a>` + "\x93hi\x94" + `</a>`, expected: `<meta http-equiv="Content-Type" content="text/html; charset=windows-1252"><a>“hi”</a>`, charset: "windows-1252"},
+		{name: "Utf-8 bom", body: "\xef\xbb\xbf<a>café</a>", contentType: "text/html; charset=windows-1252", expected: "<a>café</a>", charset: "utf-8"},
+		{name: "Utf-16 bom", body: "\xff\xfe<\x00a\x00>\x00", expected: "<a>", charset: "utf-16le"},
+		{name: "Plain utf-8", body: "<a>café</a>", expected: "<a>café</a>", charset: "utf-8"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			decoded, charset := utf8Body(strings.NewReader(tc.body), tc.contentType)
+			body, err := io.ReadAll(decoded)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(body) != tc.expected || charset != tc.charset {
+				t.Errorf("Expected %q in %s, got %q in %s", tc.expected, tc.charset, body, charset)
+			}
+		})
+	}
+}
+
+func TestCrawlCheckAssets(t *testing.T) {
+	var mu sync.Mutex
+	requests := make(map[string]int)
+	fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		requests[req.Method+" "+req.URL.Path]++
+		mu.Unlock()
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+		switch req.URL.Path {
+		case "/", "/a":
+			resp.Header.Set("Content-Type", "text/html")
+			resp.Body = io.NopCloser(strings.NewReader(`<link rel="stylesheet" href="/site.css"><img src="/missing.png"><a href="/a">A</a>`))
+		case "/site.css":
+			if req.Method == http.MethodHead {
+				resp.StatusCode = http.StatusMethodNotAllowed
+			}
+			resp.Header.Set("Content-Type", "text/css; charset=utf-8")
+			resp.ContentLength = 120
+		default:
+			resp.StatusCode = http.StatusNotFound
+		}
+		return resp, nil
+	})
+	crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(fetcher), WithHostLimits(2, 0), WithCheckAssets())
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	crawler.CrawlChildrenWithDepth(context.Background())
+	assets := crawler.Page.Assets
+	if len(assets) != 2 {
+		t.Fatalf("Expected the stylesheet and the image, got %v", assets)
+	}
+	if assets[0].Status != http.StatusOK || assets[0].ContentType != "text/css" || assets[0].Size != 120 {
+		t.Errorf("Expected the stylesheet to be checked with a GET, got %+v", assets[0])
+	}
+	if assets[1].Status != http.StatusNotFound {
+		t.Errorf("Expected the missing image to be recorded, got %+v", assets[1])
+	}
+	if requests["HEAD /site.css"] != 1 || requests["GET /site.css"] != 1 || requests["HEAD /missing.png"] != 1 {
+		t.Errorf("Expected every asset to be checked once per crawl, got %v", requests)
+	}
+
+	if assets[0].Unchecked != "" || assets[1].Unchecked != "" {
+		t.Errorf("Expected requested assets not to be unchecked, got %+v", assets)
+	}
+
+	requests = make(map[string]int)
+	crawler = New("https://example.com/", WithFetcher(fetcher), WithHostLimits(2, 0))
+	crawler.Crawl(context.Background())
+	if requests["HEAD /site.css"] != 0 || crawler.Page.Assets[0].Status != 0 {
+		t.Error("Expected assets not to be requested by default")
+	}
+}
+
+func TestCrawlUncheckedAssets(t *testing.T) {
+	fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+		switch req.URL.Path {
+		case "/robots.txt":
+			resp.Body = io.NopCloser(strings.NewReader("User-agent: *\nDisallow: /private"))
+		case "/":
+			resp.Header.Set("Content-Type", "text/html")
+			resp.Body = io.NopCloser(strings.NewReader(`<img src="http://example.com/plain.png"><img src="/private/logo.png">`))
+		}
+		return resp, nil
+	})
+	crawler := New("https://example.com/", WithFetcher(fetcher), WithHostLimits(2, 0), WithCheckAssets())
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	assets := crawler.Page.Assets
+	if len(assets) != 2 {
+		t.Fatalf("Expected both images, got %v", assets)
+	}
+	for _, asset := range assets {
+		if asset.Status != 0 || asset.Unchecked == "" {
+			t.Errorf("Expected %s to record why it wasn't requested, got %+v", asset.URL, asset)
+		}
+	}
+}
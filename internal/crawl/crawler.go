@@ -6,10 +6,9 @@ import (
 	"fmt"
 	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
 	"golang.org/x/net/html"
-	"maps"
 	"net/http"
 	"net/url"
-	"runtime"
+	"slices"
 	"strings"
 	"sync"
 	"time"
@@ -17,56 +16,306 @@ import (
 
 type Crawler struct {
 	URL              string
-	Depth            int
 	Status           int
-	TextLinksCrawled map[string]string
+	TextLinksCrawled []Link
 	LastTimeCrawled  time.Time
+	Page             PageInfo
+	Skipped          map[string]string
+	Outcomes         map[ErrorCategory]int
+	Pages            []Result
+	Config           Config
+	robots           *robotsCache
+	scheduler        *hostScheduler
+	frontier         *frontier
+	seed             *url.URL
+	stream           chan<- Result
+	streamErr        error
+	pagesCrawled     int
+	linksFound       int
+	failures         []*FetchError
+	nofollowPages    map[string]bool
+	assets           *assetCache
 }
 
 type Result struct {
+	URL       string
+	Depth     int
+	Status    int
+	FetchedAt time.Time
+	Attempts  int
+	Category  ErrorCategory
+	// NotModified is set when the page answered 304 to a conditional
+	// request, it has no links since its body wasn't downloaded.
+	NotModified bool
+	Validators  CacheValidators
+	ContentType string
+	Charset     string
+	Page        PageInfo
+	// Robots are the robots meta and X-Robots-Tag rules of the page, left
+	// empty when the config ignores them.
+	Robots RobotsDirectives
+	// Asset is set instead of the links when the url isn't an html page.
+	Asset *Asset
+	// Truncated is set when the page was bigger than the body size limit
+	// and only its beginning was parsed.
+	Truncated   bool
+	Redirects   []Redirect
 	Error       error
-	InfoCrawled map[string]string
+	InfoCrawled []Link
 }
 
-func New(url string, depth int, status int, timeOfCrawl time.Time) *Crawler {
-	p := Crawler{URL: url, Status: status, TextLinksCrawled: make(map[string]string), Depth: depth, LastTimeCrawled: timeOfCrawl}
+type Link struct {
+	Source   string
+	Target   string
+	Text     string
+	Rel      []string
+	Depth    int
+	Position int
+	// href is the resolved url the link is requested with, Target is its
+	// normalized form and tells pages apart.
+	href string
+}
+
+// requestURL returns the url a link is fetched from, links built outside the
+// extractor only have their Target.
+func (l Link) requestURL() string {
+	if l.href != "" {
+		return l.href
+	}
+	return l.Target
+}
+
+func New(url string, opts ...Option) *Crawler {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+	config.applyDefaults()
+	p := Crawler{URL: url, Config: config, Skipped: make(map[string]string), Outcomes: make(map[ErrorCategory]int), nofollowPages: make(map[string]bool), assets: newAssetCache(), robots: newRobotsCache(), scheduler: newHostScheduler(), frontier: newFrontier(config.TrackingParams)}
 	return &p
 }
 
 func (c *Crawler) String() string {
 	var b strings.Builder
-	b.WriteString(fmt.Sprintf("%s \t %d \t %d \t %s", c.URL, c.Status, len(c.TextLinksCrawled), c.LastTimeCrawled.String()))
+	links := len(c.TextLinksCrawled)
+	if c.Config.StreamOnly {
+		links = c.linksFound
+	}
+	b.WriteString(fmt.Sprintf("%s \t %d \t %d \t %s", c.URL, c.Status, links, c.LastTimeCrawled.String()))
+	b.WriteString(c.Page.String())
 	return b.String()
 }
 
-func (c *Crawler) Crawl() error {
-	crawlResult, validUrl, statusCode := crawlLink(c.URL)
+// Summary returns how many urls ended in each outcome category.
+func (c *Crawler) Summary() string {
+	return summarize(c.Outcomes)
+}
+
+func (c *Crawler) Crawl(ctx context.Context) error {
+	crawlResult, _ := c.crawlLink(ctx, c.URL, 0)
 	if crawlResult.Error != nil {
+		c.recordPage(ctx, crawlResult)
+		c.recordSkip(crawlResult.Error)
 		return crawlResult.Error
 	}
-	c.URL = validUrl.String()
-	c.Status = statusCode
-	c.TextLinksCrawled = crawlResult.InfoCrawled
-	c.LastTimeCrawled = time.Now()
-	fmt.Print("status of crawl", statusCode, c.Status)
+	c.URL = crawlResult.URL
+	if seed, err := url.Parse(c.URL); err == nil {
+		c.seed = seed
+	}
+	c.frontier.markVisited(c.URL, 0)
+	c.Status = crawlResult.Status
+	c.LastTimeCrawled = crawlResult.FetchedAt
+	c.Page = crawlResult.Page
+	c.recordPage(ctx, crawlResult)
+	for _, link := range crawlResult.InfoCrawled {
+		c.enqueueLink(ctx, link, 1)
+	}
+	fmt.Print("status of crawl", crawlResult.Status, c.Status)
 	return nil
 }
 
-func (c *Crawler) CrawlChildrenWithDepth() error {
-	for range c.Depth {
-		var linksNextDepth []string
-		for _, v := range c.TextLinksCrawled {
-			linksNextDepth = append(linksNextDepth, v)
+// CrawlChildrenWithDepth crawls the links found on the seed page level by
+// level, the seed is depth 0 so pages up to MaxDepth-1 are fetched and the
+// links found on the last level are recorded but not followed. When ctx is
+// canceled it returns early and keeps every page crawled so far.
+func (c *Crawler) CrawlChildrenWithDepth(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, c.Config.CrawlTimeout)
+	defer cancel()
+	if c.seed == nil {
+		seed, err := url.Parse(c.URL)
+		if err != nil {
+			return err
+		}
+		c.seed = seed
+	}
+	c.frontier.markVisited(c.URL, 0)
+	for _, link := range c.TextLinksCrawled {
+		c.enqueueLink(ctx, link, max(link.Depth, 1))
+	}
+	for depth := 1; depth < c.Config.MaxDepth; depth++ {
+		linksAtDepth := c.frontier.popDepth(depth)
+		if c.Config.MaxPages > 0 {
+			remaining := c.Config.MaxPages - c.pagesCrawled
+			if remaining <= 0 {
+				break
+			}
+			if len(linksAtDepth) > remaining {
+				linksAtDepth = linksAtDepth[:remaining]
+			}
+		}
+		if len(linksAtDepth) == 0 {
+			break
+		}
+		err := c.concurrentCrawl(ctx, linksAtDepth, depth, func(result Result) {
+			c.recordPage(ctx, result)
+			if result.Error != nil {
+				c.recordSkip(result.Error)
+				return
+			}
+			for _, link := range result.InfoCrawled {
+				c.enqueueLink(ctx, link, depth+1)
+			}
+		})
+		if err != nil {
+			return err
 		}
-		concurrentResult, _ := concurrentCrawl(linksNextDepth)
-		maps.Copy(c.TextLinksCrawled, concurrentResult)
+	}
+	if len(c.failures) > 0 {
+		return &CrawlErrors{Failures: c.failures}
 	}
 	return nil
 }
 
-func concurrentCrawl(links []string) (map[string]string, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+// enqueueLink queues a link found on a page unless its rel asks crawlers not
+// to follow it and the config honours that.
+func (c *Crawler) enqueueLink(ctx context.Context, link Link, depth int) {
+	if c.nofollowPages[link.Source] {
+		c.skipLink(ctx, link.Target, depth, "found on a nofollow page")
+		return
+	}
+	if c.Config.SkipNofollow && slices.Contains(link.Rel, "nofollow") {
+		c.skipLink(ctx, link.Target, depth, "nofollow link")
+		return
+	}
+	c.enqueue(ctx, link.requestURL(), depth)
+}
+
+// enqueue queues a link found at the given depth, links left out of the
+// crawl are delivered right away as out of scope results.
+func (c *Crawler) enqueue(ctx context.Context, link string, depth int) {
+	u, err := validate.ValidateWithPolicy(link, c.Config.SchemePolicy)
+	if errors.Is(err, validate.ErrSchemeNotAllowed) {
+		c.skipLink(ctx, link, depth, err.Error())
+		return
+	} else if err != nil {
+		return
+	}
+	key, err := validate.NormalizeURL(u, c.Config.TrackingParams)
+	if err != nil {
+		return
+	}
+	if c.Config.Scope != nil && !c.Config.Scope(key) {
+		c.skipLink(ctx, key.String(), depth, "out of scope")
+		return
+	}
+	if ok, reason := c.Config.ScopeRules.Allows(c.seed, key); !ok {
+		c.skipLink(ctx, key.String(), depth, reason)
+		return
+	}
+	c.frontier.add(link, depth)
+}
+
+func (c *Crawler) FoundAtDepth(link string) (int, bool) {
+	return c.frontier.depthOf(link)
+}
+
+// Stream runs the whole crawl in the background and delivers every page as
+// soon as it is fetched, the channel is closed when the crawl ends and Err
+// reports why it stopped.
+func (c *Crawler) Stream(ctx context.Context) <-chan Result {
+	pages := make(chan Result)
+	c.stream = pages
+	go func() {
+		defer close(pages)
+		err := c.Crawl(ctx)
+		if err == nil && c.Config.MaxDepth > 1 {
+			err = c.CrawlChildrenWithDepth(ctx)
+		}
+		c.streamErr = err
+		c.stream = nil
+	}()
+	return pages
+}
+
+// Err returns the error that stopped the last Stream, it must only be called
+// once the channel was closed.
+func (c *Crawler) Err() error {
+	return c.streamErr
+}
+
+// recordPage is only called from the goroutine driving the crawl, so it can
+// update the crawler without locking.
+func (c *Crawler) recordPage(ctx context.Context, result Result) {
+	result.Category = Categorize(result)
+	c.pagesCrawled++
+	c.linksFound += len(result.InfoCrawled)
+	if len(result.Redirects) > 0 && result.Error == nil {
+		c.frontier.markVisited(result.URL, result.Depth)
+	}
+	if result.Robots.NoFollow {
+		c.nofollowPages[result.URL] = true
+	}
+	if !c.Config.StreamOnly {
+		c.Pages = append(c.Pages, result)
+		c.TextLinksCrawled = append(c.TextLinksCrawled, result.InfoCrawled...)
+	}
+	if c.Config.Hooks.OnPage != nil {
+		c.Config.Hooks.OnPage(result)
+	}
+	c.deliver(ctx, result)
+}
+
+// deliver counts the outcome of result and sends it to the stream.
+func (c *Crawler) deliver(ctx context.Context, result Result) {
+	c.Outcomes[result.Category]++
+	if result.Category.Failed() {
+		c.failures = append(c.failures, newFetchError(result))
+	}
+	if c.stream != nil {
+		select {
+		case c.stream <- result:
+		case <-ctx.Done():
+		}
+	}
+}
+
+func (c *Crawler) recordSkip(err error) {
+	var skipErr *SkipError
+	if errors.As(err, &skipErr) && !errors.Is(err, ErrCanceled) {
+		c.skip(skipErr.URL, skipErr.Reason)
+	}
+}
+
+func (c *Crawler) skipLink(ctx context.Context, link string, depth int, reason string) {
+	if _, ok := c.Skipped[link]; ok {
+		return
+	}
+	c.skip(link, reason)
+	err := &SkipError{URL: link, Reason: reason, Category: CategoryScope}
+	c.deliver(ctx, Result{URL: link, Depth: depth, Error: err, Category: CategoryScope})
+}
+
+func (c *Crawler) skip(link string, reason string) {
+	if _, ok := c.Skipped[link]; ok {
+		return
+	}
+	c.Skipped[link] = reason
+	if c.Config.Hooks.OnSkip != nil {
+		c.Config.Hooks.OnSkip(link, reason)
+	}
+}
+
+func (c *Crawler) concurrentCrawl(ctx context.Context, links []string, depth int, onResult func(Result)) error {
 	linkChannelGenerator := func(ctx context.Context, receivedLinks ...string) <-chan string {
 		linkStream := make(chan string)
 		go func() {
@@ -91,8 +340,12 @@ func concurrentCrawl(links []string) (map[string]string, error) {
 					if !ok {
 						return
 					}
-					crawlResult, _, _ := crawlLink(link)
-					results <- crawlResult
+					crawlResult, _ := c.crawlLink(ctx, link, depth)
+					select {
+					case results <- crawlResult:
+					case <-ctx.Done():
+						return
+					}
 				case <-ctx.Done():
 					return
 				}
@@ -123,83 +376,556 @@ func concurrentCrawl(links []string) (map[string]string, error) {
 		}()
 		return multiplexedStream
 	}
-	linkChannel := linkChannelGenerator(ctx, links...)
-	numCrawlers := runtime.NumCPU()
+	linkChannel := linkChannelGenerator(ctx, interleaveByHost(links)...)
+	numCrawlers := c.Config.Workers
 	fmt.Printf("\nWe are going to create %d number of goroutines to make the crawl concurrent", numCrawlers)
 	crawlersChan := make([]<-chan Result, numCrawlers)
 	for i := range numCrawlers {
 		crawlersChan[i] = crawlerWorker(ctx, linkChannel)
 	}
 
-	textAndLinksCrawled := make(map[string]string)
-	for c := range fanIn(ctx, crawlersChan...) {
-		maps.Copy(textAndLinksCrawled, c.InfoCrawled)
+	for result := range fanIn(ctx, crawlersChan...) {
+		onResult(result)
 	}
 	if err := ctx.Err(); err != nil {
 		if errors.Is(err, context.DeadlineExceeded) {
-			return textAndLinksCrawled, fmt.Errorf("Crawl stopped: timeout exceeded: %v ", err)
+			return fmt.Errorf("Crawl stopped: timeout exceeded: %w", err)
 		} else if errors.Is(err, context.Canceled) {
-			return textAndLinksCrawled, fmt.Errorf("Crawl stopped: canceled by user: %v", err)
+			return fmt.Errorf("Crawl stopped: canceled by user: %w", err)
 		} else {
-			return textAndLinksCrawled, fmt.Errorf("Crawl stopped: %v", err)
+			return fmt.Errorf("Crawl stopped: %w", err)
 		}
 	}
-	return textAndLinksCrawled, nil
+	return nil
 }
 
-func retrieveUrlData(baseUrl *url.URL, tz *html.Tokenizer) (map[string]string, error) {
-	textAndLinks := make(map[string]string)
+// retrieveUrlData extracts the links of a page along with its title, meta
+// tags, language, canonical url, headings and word count.
+func retrieveUrlData(baseUrl *url.URL, tz *html.Tokenizer, depth int, trackingParams []string) (PageInfo, []Link, error) {
+	var page PageInfo
+	var textAndLinks []Link
+	resolveBase := baseUrl
+	hasBase := false
+	source := normalizeLink(baseUrl, trackingParams)
+	var mediaParent string
+	seenAssets := make(map[[2]string]bool)
+	addAsset := func(rawUrl, kind string) {
+		rawUrl = strings.TrimSpace(rawUrl)
+		if rawUrl == "" {
+			return
+		}
+		assetUrl, err := resolveBase.Parse(rawUrl)
+		if err != nil || (assetUrl.Scheme != "http" && assetUrl.Scheme != "https") {
+			return
+		}
+		key := [2]string{normalizeLink(assetUrl, trackingParams), kind}
+		if seenAssets[key] {
+			return
+		}
+		seenAssets[key] = true
+		page.Assets = append(page.Assets, Asset{Source: source, URL: key[0], Kind: kind, href: withoutFragment(assetUrl)})
+	}
+	var hidden []string
+	var heading *Heading
+	var headingText strings.Builder
+	inTitle := false
+	var body textExtractor
+	var anchor *anchorText
+	closeAnchor := func() {
+		if anchor != nil {
+			textAndLinks[anchor.link].Text = anchor.text()
+			anchor = nil
+		}
+	}
 	for {
 		tt := tz.Next()
 		if tt == html.ErrorToken {
 			break
 		}
-		if tt == html.StartTagToken {
+		switch tt {
+		case html.StartTagToken, html.SelfClosingTagToken:
 			t := tz.Token()
-			if t.Data == "a" {
-				var link string
-				tokenAttributes := t.Attr
-				for _, value := range tokenAttributes {
-					if value.Key == "href" {
+			if hiddenText[t.Data] && tt == html.StartTagToken {
+				hidden = append(hidden, t.Data)
+			}
+			body.start(t.Data, attr(t, "class"), attr(t, "id"), attr(t, "role"), tt == html.SelfClosingTagToken)
+			switch t.Data {
+			case "a":
+				closeAnchor()
+				var link, href string
+				var rel []string
+				for _, value := range t.Attr {
+					switch value.Key {
+					case "href":
 						trimmedUrl := strings.TrimSpace(value.Val)
 						anchorUrl, err := url.Parse(trimmedUrl)
 						if err != nil {
 							fmt.Printf("skipping malformed href: %s\n", err)
 							continue
 						}
-						link = baseUrl.ResolveReference(anchorUrl).String()
+						resolved := resolveBase.ResolveReference(anchorUrl)
+						link = normalizeLink(resolved, trackingParams)
+						href = withoutFragment(resolved)
+					case "rel":
+						rel = strings.Fields(strings.ToLower(value.Val))
+					}
+				}
+				if link == "" {
+					continue
+				}
+				textAndLinks = append(textAndLinks, Link{
+					Source:   source,
+					Target:   link,
+					Rel:      rel,
+					Depth:    depth,
+					Position: len(textAndLinks),
+					href:     href,
+				})
+				anchor = &anchorText{link: len(textAndLinks) - 1, ariaLabel: attr(t, "aria-label"), title: attr(t, "title")}
+			case "img":
+				if anchor != nil {
+					anchor.alt.WriteString(attr(t, "alt"))
+					anchor.alt.WriteString(" ")
+				}
+				addAsset(attr(t, "src"), AssetImage)
+				for _, src := range srcsetURLs(attr(t, "srcset")) {
+					addAsset(src, AssetImage)
+				}
+			case "script":
+				addAsset(attr(t, "src"), AssetScript)
+			case "iframe", "frame":
+				addAsset(attr(t, "src"), AssetFrame)
+			case "video", "audio", "picture":
+				if tt == html.StartTagToken {
+					mediaParent = t.Data
+				}
+				if t.Data != "picture" {
+					addAsset(attr(t, "src"), sourceKind(t.Data))
+				}
+				addAsset(attr(t, "poster"), AssetImage)
+			case "source":
+				addAsset(attr(t, "src"), sourceKind(mediaParent))
+				for _, src := range srcsetURLs(attr(t, "srcset")) {
+					addAsset(src, sourceKind(mediaParent))
+				}
+			case "title":
+				inTitle = page.Title == ""
+			case "html":
+				page.Lang = strings.TrimSpace(attr(t, "lang"))
+			case "base":
+				if href := strings.TrimSpace(attr(t, "href")); href != "" && !hasBase {
+					if base, err := baseUrl.Parse(href); err == nil {
+						resolveBase = base
+						hasBase = true
+					}
+				}
+			case "meta":
+				content := strings.TrimSpace(attr(t, "content"))
+				if strings.EqualFold(attr(t, "http-equiv"), "refresh") && page.Refresh == "" {
+					if target, delay, ok := refreshURL(content); ok {
+						if refresh, err := resolveBase.Parse(target); err == nil {
+							page.Refresh = withoutFragment(refresh)
+							page.RefreshDelay = delay
+						}
+					}
+				}
+				name := strings.ToLower(strings.TrimSpace(attr(t, "name")))
+				if name != "" {
+					if page.meta == nil {
+						page.meta = make(map[string]string)
 					}
+					if previous := page.meta[name]; previous != "" {
+						page.meta[name] = previous + "," + content
+					} else {
+						page.meta[name] = content
+					}
+				}
+				switch name {
+				case "description":
+					page.Description = content
+				case "keywords":
+					page.Keywords = content
+				case "robots":
+					page.Robots = content
+				}
+			case "link":
+				rel := strings.Fields(strings.ToLower(attr(t, "rel")))
+				if kind := linkAssetKind(rel, attr(t, "as")); kind != "" {
+					addAsset(attr(t, "href"), kind)
 				}
-				nextToken := tz.Next()
-				if nextToken == html.TextToken && link != "" {
-					trimmedText := strings.TrimSpace(string(tz.Text()))
-					if trimmedText != "" {
-						textAndLinks[trimmedText] = link
+				if slices.Contains(rel, "canonical") {
+					if canonical, err := resolveBase.Parse(strings.TrimSpace(attr(t, "href"))); err == nil {
+						page.Canonical = normalizeLink(canonical, trackingParams)
 					}
 				}
+			default:
+				if level := headingLevel(t.Data); level > 0 && heading == nil && tt == html.StartTagToken {
+					heading = &Heading{Level: level}
+					headingText.Reset()
+				}
+			}
+		case html.EndTagToken:
+			t := tz.Token()
+			if len(hidden) > 0 && hidden[len(hidden)-1] == t.Data {
+				hidden = hidden[:len(hidden)-1]
+			}
+			body.end(t.Data)
+			if t.Data == "title" {
+				inTitle = false
+			}
+			if t.Data == "a" {
+				closeAnchor()
+			}
+			if t.Data == mediaParent {
+				mediaParent = ""
+			}
+			if heading != nil && headingLevel(t.Data) == heading.Level {
+				heading.Text = collapseSpaces(headingText.String())
+				page.Headings = append(page.Headings, *heading)
+				heading = nil
 			}
+		case html.TextToken:
+			text := string(tz.Text())
+			if inTitle {
+				page.Title = collapseSpaces(text)
+			}
+			if len(hidden) > 0 {
+				continue
+			}
+			if anchor != nil {
+				anchor.content.WriteString(text)
+			}
+			if heading != nil {
+				headingText.WriteString(text)
+				headingText.WriteString(" ")
+			}
+			body.text(text)
+			page.WordCount += len(strings.Fields(text))
 		}
 	}
-	return textAndLinks, nil
+	closeAnchor()
+	page.Text = body.result()
+	return page, textAndLinks, nil
 }
 
-func crawlLink(link string) (Result, *url.URL, int) {
-	validatedUrl, err := validate.ValidateAndParseUrl(link)
+func attr(t html.Token, key string) string {
+	for _, value := range t.Attr {
+		if value.Key == key {
+			return value.Val
+		}
+	}
+	return ""
+}
+
+// withoutFragment returns u as it is requested, fragments never reach the
+// server.
+func withoutFragment(u *url.URL) string {
+	res := *u
+	res.Fragment = ""
+	res.RawFragment = ""
+	return res.String()
+}
+
+func normalizeLink(u *url.URL, trackingParams []string) string {
+	normalized, err := validate.NormalizeURL(u, trackingParams)
 	if err != nil {
-		return Result{Error: err, InfoCrawled: nil}, nil, 0
+		return u.String()
+	}
+	return normalized.String()
+}
+
+func (c *Crawler) crawlLink(ctx context.Context, link string, depth int) (Result, *url.URL) {
+	result := Result{URL: link, Depth: depth}
+	validatedUrl, err := validate.ValidateWithPolicy(link, c.Config.SchemePolicy)
+	if errors.Is(err, validate.ErrSchemeNotAllowed) {
+		result.Error = &SkipError{URL: link, Reason: err.Error(), Category: CategoryScope}
+		return result, nil
+	} else if err != nil {
+		result.Error = err
+		return result, nil
+	}
+	result, fetchedUrl := c.fetchPage(ctx, validatedUrl, result)
+	if c.Config.CheckAssets && fetchedUrl != nil {
+		c.checkAssets(ctx, result.Page.Assets)
+	}
+	var skipErr *SkipError
+	upgraded := c.Config.SchemePolicy == validate.UpgradeHTTP && strings.HasPrefix(strings.ToLower(link), "http:")
+	unreachable := !errors.As(result.Error, &skipErr) || skipErr.Reason == robotsUnreachableReason
+	if upgraded && fetchedUrl == nil && result.Status == 0 && unreachable && ctx.Err() == nil {
+		plainUrl := *validatedUrl
+		plainUrl.Scheme = "http"
+		result, fetchedUrl = c.fetchPage(ctx, &plainUrl, Result{URL: link, Depth: depth})
+		if c.Config.CheckAssets && fetchedUrl != nil {
+			c.checkAssets(ctx, result.Page.Assets)
+		}
+	}
+	if result.Error != nil && ctx.Err() != nil {
+		result.Error = fmt.Errorf("%w: %w", ErrCanceled, result.Error)
+	}
+	// pages are requested as linked but told apart by their normalized url
+	if key, ok := normalizeKey(result.URL, c.Config.TrackingParams); ok {
+		result.URL = key
+	}
+	if result.Asset != nil {
+		result.Asset.URL = result.URL
+	}
+	return result, fetchedUrl
+}
+
+// fetchPage downloads an already validated url and extracts its links, the
+// returned url is nil when the page couldn't be crawled.
+func (c *Crawler) fetchPage(ctx context.Context, validatedUrl *url.URL, result Result) (Result, *url.URL) {
+	if c.Config.HeadUnknown && !knownHTMLExtension(validatedUrl.Path) {
+		if asset, ok := c.headAsset(ctx, validatedUrl, &result); ok {
+			result.Status = asset.Status
+			result.URL = validatedUrl.String()
+			result.ContentType = asset.ContentType
+			result.Asset = asset
+			return result, validatedUrl
+		}
 	}
-	client := &http.Client{
-		Timeout: 30 * time.Second,
+	seen := map[string]bool{validatedUrl.String(): true}
+	current := validatedUrl
+	for {
+		resp, release, err := c.fetchOnce(ctx, http.MethodGet, current, &result)
+		if err != nil {
+			result.Error = err
+			return result, nil
+		}
+		var location string
+		if isRedirect(resp.StatusCode) {
+			location = resp.Header.Get("Location")
+			result.Status = resp.StatusCode
+			resp.Body.Close()
+			release()
+		} else {
+			var fetchedUrl *url.URL
+			result, fetchedUrl = c.readPage(current, resp, result)
+			resp.Body.Close()
+			release()
+			// a meta refresh to another url is followed like a redirect unless
+			// it waits long enough for the page to be read first
+			if fetchedUrl == nil || result.Page.Refresh == "" || result.Page.Refresh == current.String() || result.Page.RefreshDelay > maxRefreshDelay {
+				return result, fetchedUrl
+			}
+			location = result.Page.Refresh
+		}
+		result.Redirects = append(result.Redirects, Redirect{URL: current.String(), Status: result.Status})
+		next, err := current.Parse(location)
+		if location == "" || err != nil {
+			result.Error = fmt.Errorf("%w: %s answered %d with location %q", ErrRedirectNoLocation, current, result.Status, location)
+			return result, nil
+		}
+		nextUrl, err := validate.ValidateWithPolicy(next.String(), c.Config.SchemePolicy)
+		if errors.Is(err, validate.ErrSchemeNotAllowed) {
+			result.Error = &SkipError{URL: next.String(), Reason: err.Error(), Category: CategoryScope}
+			return result, nil
+		} else if err != nil {
+			result.Error = fmt.Errorf("%w: %v", ErrRedirectNoLocation, err)
+			return result, nil
+		}
+		if seen[nextUrl.String()] {
+			result.Error = fmt.Errorf("%w: %s was already visited", ErrRedirectLoop, nextUrl)
+			return result, nil
+		}
+		if len(result.Redirects) > c.Config.MaxRedirects {
+			result.Error = fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, c.Config.MaxRedirects)
+			return result, nil
+		}
+		seen[nextUrl.String()] = true
+		current = nextUrl
 	}
-	resp, err := client.Get(validatedUrl.String())
+}
+
+// readPage parses the body of the final response of a page, bodies that
+// aren't html are recorded as an asset without being read.
+func (c *Crawler) readPage(current *url.URL, resp *http.Response, result Result) (Result, *url.URL) {
+	result.Page = PageInfo{}
+	result.InfoCrawled = nil
+	result.Truncated = false
+	result.Status = resp.StatusCode
+	result.URL = current.String()
+	result.Validators = validatorsOf(resp)
+	if resp.StatusCode == http.StatusNotModified {
+		result.NotModified = true
+		links, stored := c.storedLinks(current, result.Depth+1)
+		result.InfoCrawled = links
+		if !c.Config.IgnoreRobotsMeta {
+			result.Robots = robotsDirectives(c.Config.UserAgent, nil, resp.Header.Values("X-Robots-Tag"))
+			result.Robots.NoIndex = result.Robots.NoIndex || stored.NoIndex
+			result.Robots.NoFollow = result.Robots.NoFollow || stored.NoFollow
+		}
+		return result, current
+	}
+	contentType, body := sniffContentType(resp)
+	result.ContentType = mediaType(contentType)
+	if !isHTML(contentType) {
+		if resp.StatusCode < 300 {
+			result.Asset = newAsset(result.URL, resp, contentType)
+		}
+		return result, current
+	}
+	limited := &limitedBody{r: body, remaining: c.Config.MaxBodySize}
+	decoded, charsetName := utf8Body(limited, resp.Header.Get("Content-Type"))
+	result.Charset = charsetName
+	tokenizer := html.NewTokenizer(decoded)
+	page, textAndLinks, err := retrieveUrlData(current, tokenizer, result.Depth+1, c.Config.TrackingParams)
+	if err != nil {
+		result.Error = fmt.Errorf("%w: %v", ErrParse, err)
+		return result, nil
+	}
+	result.Page = page
+	if !c.Config.IgnoreRobotsMeta {
+		result.Robots = robotsDirectives(c.Config.UserAgent, page.meta, resp.Header.Values("X-Robots-Tag"))
+	}
+	result.Truncated = limited.truncated
+	result.InfoCrawled = textAndLinks
+	return result, current
+}
+
+// storedLinks returns the links saved for an unchanged page at the depth they
+// are found on now, with the robots directives its meta tags had.
+func (c *Crawler) storedLinks(page *url.URL, depth int) ([]Link, RobotsDirectives) {
+	if c.Config.StoredLinks == nil {
+		return nil, RobotsDirectives{}
+	}
+	links, directives, ok := c.Config.StoredLinks(normalizeLink(page, c.Config.TrackingParams))
+	if !ok {
+		return nil, RobotsDirectives{}
+	}
+	for i := range links {
+		links[i].Depth = depth
+	}
+	return links, directives
+}
+
+// headAsset asks for the headers of a url that doesn't look like a page, ok
+// is only true when the server answered and the url is not html.
+func (c *Crawler) headAsset(ctx context.Context, target *url.URL, result *Result) (*Asset, bool) {
+	resp, release, err := c.fetchOnce(ctx, http.MethodHead, target, result)
 	if err != nil {
-		return Result{Error: fmt.Errorf("error trying to perform get to the url, %v", err), InfoCrawled: nil}, nil, resp.StatusCode
+		return nil, false
 	}
+	defer release()
 	defer resp.Body.Close()
-	tokenizer := html.NewTokenizer(resp.Body)
-	textAndLinks, err := retrieveUrlData(validatedUrl, tokenizer)
+	contentType := resp.Header.Get("Content-Type")
+	if resp.StatusCode >= 300 || contentType == "" || isHTML(contentType) {
+		return nil, false
+	}
+	return newAsset(target.String(), resp, contentType), true
+}
+
+// checkAssets fills the status, content type and size of the assets of a
+// page, every asset is requested once per crawl.
+func (c *Crawler) checkAssets(ctx context.Context, assets []Asset) {
+	for i := range assets {
+		if ctx.Err() != nil {
+			return
+		}
+		checked, ok := c.assets.get(assets[i].URL)
+		if !ok {
+			checked = c.checkAsset(ctx, assets[i].requestURL())
+			c.assets.set(assets[i].URL, checked)
+		}
+		assets[i].Status = checked.Status
+		assets[i].ContentType = checked.ContentType
+		assets[i].Size = checked.Size
+		assets[i].Unchecked = checked.Unchecked
+	}
+}
+
+// checkAsset sends a HEAD request for an asset, falling back to GET for
+// servers that don't support it. Assets that can't be fetched keep a 0 status,
+// the ones that weren't requested at all also record why.
+func (c *Crawler) checkAsset(ctx context.Context, assetUrl string) Asset {
+	asset := Asset{URL: assetUrl}
+	target, err := validate.ValidateWithPolicy(assetUrl, c.Config.SchemePolicy)
 	if err != nil {
-		return Result{Error: err, InfoCrawled: nil}, nil, resp.StatusCode
+		asset.Unchecked = err.Error()
+		return asset
+	}
+	var result Result
+	for _, method := range []string{http.MethodHead, http.MethodGet} {
+		resp, release, err := c.fetchOnce(ctx, method, target, &result)
+		var skipErr *SkipError
+		if errors.As(err, &skipErr) {
+			asset.Unchecked = skipErr.Reason
+			return asset
+		} else if err != nil && ctx.Err() != nil {
+			asset.Unchecked = ErrCanceled.Error()
+			return asset
+		} else if err != nil {
+			return asset
+		}
+		resp.Body.Close()
+		release()
+		asset.Status = resp.StatusCode
+		asset.ContentType = mediaType(resp.Header.Get("Content-Type"))
+		asset.Size = max(resp.ContentLength, 0)
+		if resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+			break
+		}
+	}
+	return asset
+}
+
+// fetchOnce requests a single url honouring robots.txt, the host scheduler
+// and the retry policy, the caller must close the body and call release.
+func (c *Crawler) fetchOnce(ctx context.Context, method string, target *url.URL, result *Result) (*http.Response, func(), error) {
+	if err := c.checkRobots(ctx, target); err != nil {
+		return nil, nil, err
+	}
+	rules, _ := c.robotsRules(ctx, target)
+	delay := max(c.Config.HostDelay, rules.CrawlDelay(c.Config.UserAgent))
+	var validators CacheValidators
+	if c.Config.Validators != nil {
+		validators, _ = c.Config.Validators(normalizeLink(target, c.Config.TrackingParams))
+	}
+	resp, release, err := c.send(ctx, method, target, validators, delay, result)
+	var blocked *BlockedAddressError
+	if errors.As(err, &blocked) {
+		return nil, nil, &SkipError{URL: target.String(), Reason: blocked.Error(), Category: CategoryScope}
+	}
+	return resp, release, err
+}
+
+// send requests target through the host scheduler, sending it again while
+// the retry policy allows. The caller must close the body and call release.
+func (c *Crawler) send(ctx context.Context, method string, target *url.URL, validators CacheValidators, delay time.Duration, result *Result) (*http.Response, func(), error) {
+	for attempt := 1; ; attempt++ {
+		release, err := c.scheduler.acquire(ctx, target.Host, c.Config.MaxPerHost, delay)
+		if err != nil {
+			return nil, nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, method, target.String(), nil)
+		if err != nil {
+			release()
+			return nil, nil, err
+		}
+		req.Header.Set("User-Agent", c.Config.UserAgent)
+		validators.apply(req)
+		result.FetchedAt = time.Now()
+		result.Attempts = attempt
+		resp, err := c.Config.Fetcher.Fetch(req)
+		if err == nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+			if until, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				c.scheduler.backoff(target.Host, until)
+			}
+		}
+		wait, retry := c.Config.Retry.retry(ctx, attempt, resp, err)
+		if !retry {
+			if err != nil {
+				release()
+				return nil, nil, fmt.Errorf("error trying to perform get to the url after %d attempts, %w", attempt, err)
+			}
+			return resp, release, nil
+		}
+		if resp != nil {
+			resp.Body.Close()
+		}
+		release()
+		if err := sleep(ctx, wait); err != nil {
+			return nil, nil, err
+		}
 	}
-	return Result{Error: err, InfoCrawled: textAndLinks}, validatedUrl, resp.StatusCode
 }
@@ -1,8 +1,17 @@
 package crawl
 
 import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"testing"
-	"time"
+
+	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
+	"golang.org/x/net/html"
 )
 
 func TestCrawlPage(t *testing.T) {
@@ -37,8 +46,8 @@ func TestCrawlPage(t *testing.T) {
 	}
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			crawler := New(tc.input, tc.depth, 0, time.Now())
-			err := crawler.Crawl()
+			crawler := New(tc.input, WithMaxDepth(tc.depth))
+			err := crawler.Crawl(context.Background())
 			var errorExistence bool = err != nil
 			if errorExistence != tc.expect_error {
 				t.Errorf("Expected an error, got %v", err)
@@ -57,6 +66,282 @@ var testLinks = []string{"https://httpbin.org/", "https://wikipedia.com", "https
 
 func BenchmarkCrawlPipelineApproach(b *testing.B) {
 	for b.Loop() {
-		concurrentCrawl(testLinks)
+		New("").concurrentCrawl(context.Background(), testLinks, 1, func(Result) {})
+	}
+}
+
+func TestRetrieveUrlDataKeepsEveryLink(t *testing.T) {
+	page := `<html><body>
+		<a href="/first">Read more</a>
+		<a href="/second" rel="nofollow UGC">Read more</a>
+		<a href="https://other.com/">Other</a>
+		<a name="no-href">Anchor</a>
+	</body></html>`
+	baseUrl, _ := url.Parse("https://example.com/blog/")
+	_, links, err := retrieveUrlData(baseUrl, html.NewTokenizer(strings.NewReader(page)), 1, validate.DefaultTrackingParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 3 {
+		t.Fatalf("Expected 3 links, got %d: %v", len(links), links)
+	}
+	if links[0].Target != "https://example.com/first" || links[1].Target != "https://example.com/second" {
+		t.Errorf("Expected both links labelled the same to be kept, got %v", links)
+	}
+	if len(links[1].Rel) != 2 || links[1].Rel[0] != "nofollow" || links[1].Rel[1] != "ugc" {
+		t.Errorf("Expected rel values to be recorded, got %v", links[1].Rel)
+	}
+	for i, link := range links {
+		if link.Position != i || link.Depth != 1 || link.Source != "https://example.com/blog" {
+			t.Errorf("Unexpected link metadata %+v", link)
+		}
+	}
+}
+
+func fakeSite(pages map[string]string, fetched map[string]int) Fetcher {
+	var mu sync.Mutex
+	return FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		fetched[req.URL.String()]++
+		mu.Unlock()
+		body, ok := pages[req.URL.String()]
+		status := http.StatusOK
+		if !ok {
+			status = http.StatusNotFound
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"text/html; charset=utf-8"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+}
+
+var testSite = map[string]string{
+	"https://example.com/robots.txt": "User-agent: *\nDisallow: /private",
+	"https://example.com/":           `<a href="/a">A</a><a href="/private/x">Private</a><a href="/a#top">A again</a>`,
+	"https://example.com/a":          `<a href="/b">B</a><a href="/">Home</a>`,
+	"https://example.com/b":          `<a href="/c">C</a>`,
+}
+
+func TestCrawlWithFetcher(t *testing.T) {
+	fetched := make(map[string]int)
+	crawler := New("https://example.com/", WithMaxDepth(3), WithFetcher(fakeSite(testSite, fetched)), WithHostLimits(2, 0))
+	err := crawler.Crawl(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = crawler.CrawlChildrenWithDepth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range []string{"https://example.com/", "https://example.com/a", "https://example.com/b"} {
+		if fetched[page] != 1 {
+			t.Errorf("Expected %s to be fetched once, got %d", page, fetched[page])
+		}
+	}
+	if fetched["https://example.com/c"] != 0 {
+		t.Error("Expected the crawl to stop at the configured depth")
+	}
+	if fetched["https://example.com/private/x"] != 0 {
+		t.Error("Expected the disallowed page not to be fetched")
+	}
+	if _, ok := crawler.Skipped["https://example.com/private/x"]; !ok {
+		t.Errorf("Expected the disallowed page to be recorded as skipped, got %v", crawler.Skipped)
+	}
+	if depth, ok := crawler.FoundAtDepth("https://example.com/c"); !ok || depth != 3 {
+		t.Errorf("Expected the last link to be found at depth 3, got %d %v", depth, ok)
+	}
+	if crawler.Status != http.StatusOK {
+		t.Errorf("Expected the seed status to be recorded, got %d", crawler.Status)
+	}
+	for _, page := range crawler.Pages {
+		if page.Error == nil && (page.Status == 0 || page.FetchedAt.IsZero()) {
+			t.Errorf("Expected every fetched page to carry its status and fetch time, got %+v", page)
+		}
+	}
+}
+
+func TestCrawlOptions(t *testing.T) {
+	fetched := make(map[string]int)
+	var pagesSeen []string
+	var skippedSeen []string
+	crawler := New("https://example.com/",
+		WithMaxDepth(3),
+		WithMaxPages(2),
+		WithWorkers(1),
+		WithUserAgent("testbot"),
+		WithFetcher(fakeSite(testSite, fetched)),
+		WithHostLimits(1, 0),
+		WithScope(func(u *url.URL) bool { return !strings.HasPrefix(u.Path, "/private") }),
+		WithOnPage(func(r Result) { pagesSeen = append(pagesSeen, r.URL) }),
+		WithOnSkip(func(link string, reason string) { skippedSeen = append(skippedSeen, link) }),
+	)
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(crawler.Pages) != 2 || len(pagesSeen) != 2 {
+		t.Errorf("Expected the crawl to stop after 2 pages, got %d pages and %d hook calls", len(crawler.Pages), len(pagesSeen))
+	}
+	if fetched["https://example.com/b"] != 0 {
+		t.Error("Expected no page to be fetched after the page limit")
+	}
+	if len(skippedSeen) != 1 || crawler.Skipped["https://example.com/private/x"] != "out of scope" {
+		t.Errorf("Expected the private link to be skipped as out of scope, got %v", crawler.Skipped)
+	}
+}
+
+func TestDefaultConfig(t *testing.T) {
+	crawler := New("https://example.com/")
+	if crawler.Config.MaxDepth != 1 || crawler.Config.UserAgent != DefaultUserAgent || crawler.Config.Workers < 1 {
+		t.Errorf("Unexpected default config %+v", crawler.Config)
+	}
+	if crawler.Config.Fetcher == nil {
+		t.Error("Expected a default fetcher to be set")
+	}
+}
+
+func TestCrawlChildrenCanceled(t *testing.T) {
+	fetched := make(map[string]int)
+	ctx, cancel := context.WithCancel(context.Background())
+	crawler := New("https://example.com/", WithMaxDepth(3), WithFetcher(fakeSite(testSite, fetched)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(ctx); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	err := crawler.CrawlChildrenWithDepth(ctx)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the crawl to stop with context.Canceled, got %v", err)
+	}
+	if len(crawler.TextLinksCrawled) == 0 {
+		t.Error("Expected the links crawled before the cancel to be kept")
+	}
+	if fetched["https://example.com/b"] != 0 {
+		t.Error("Expected no page to be fetched after the cancel")
+	}
+}
+
+func TestStream(t *testing.T) {
+	fetched := make(map[string]int)
+	crawler := New("https://example.com/", WithMaxDepth(3), WithFetcher(fakeSite(testSite, fetched)), WithHostLimits(2, 0), WithStreamOnly())
+	var pages []Result
+	for page := range crawler.Stream(context.Background()) {
+		pages = append(pages, page)
+	}
+	if err := crawler.Err(); err != nil {
+		t.Fatal(err)
+	}
+	fetchedPages := 0
+	for _, page := range pages {
+		if page.Error == nil {
+			fetchedPages++
+		}
+	}
+	if len(pages) != 4 || fetchedPages != 3 {
+		t.Fatalf("Expected 3 fetched pages and 1 skipped to be streamed, got %d of %d", fetchedPages, len(pages))
+	}
+	if pages[0].Depth != 0 || pages[0].URL != "https://example.com/" {
+		t.Errorf("Expected the seed to be streamed first, got %+v", pages[0])
+	}
+	if len(crawler.Pages) != 0 || len(crawler.TextLinksCrawled) != 0 {
+		t.Error("Expected a stream only crawler not to keep the pages in memory")
+	}
+}
+
+func TestSchemePolicy(t *testing.T) {
+	site := map[string]string{
+		"http://plain.com/":           `<a href="http://plain.com/a">A</a><a href="mailto:me@plain.com">Mail</a>`,
+		"http://plain.com/a":          `<a href="/b">B</a>`,
+		"http://plain.com/robots.txt": "",
+	}
+	fetched := make(map[string]int)
+	crawler := New("http://plain.com/", WithFetcher(fakeSite(site, fetched)), WithHostLimits(2, 0))
+	err := crawler.Crawl(context.Background())
+	var skipErr *SkipError
+	if !errors.As(err, &skipErr) || crawler.Skipped["http://plain.com/"] == "" {
+		t.Fatalf("Expected an http seed to be rejected and recorded by default, got %v", err)
+	}
+
+	crawler = New("http://plain.com/", WithMaxDepth(2), WithFetcher(fakeSite(site, fetched)), WithHostLimits(2, 0), WithSchemePolicy(validate.AllowHTTP))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fetched["http://plain.com/a"] != 1 {
+		t.Error("Expected http links to be crawled when http is allowed")
+	}
+	if _, ok := crawler.Skipped["mailto:me@plain.com"]; !ok {
+		t.Errorf("Expected the mailto link to be recorded as rejected, got %v", crawler.Skipped)
+	}
+
+	noTLS := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Scheme == "https" {
+			return nil, errors.New("tls handshake failed")
+		}
+		return fakeSite(site, fetched).Fetch(req)
+	})
+	crawler = New("http://plain.com/", WithFetcher(noTLS), WithHostLimits(2, 0), WithSchemePolicy(validate.UpgradeHTTP))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if crawler.URL != "http://plain.com/" {
+		t.Errorf("Expected the upgrade to fall back to http, got %s", crawler.URL)
+	}
+}
+
+func TestSkipNofollow(t *testing.T) {
+	site := map[string]string{
+		"https://example.com/": `<a href="/followed">Followed</a><a href="/sponsored" rel="sponsored nofollow">Ad</a>`,
+	}
+	fetched := make(map[string]int)
+	crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(fakeSite(site, fetched)), WithHostLimits(2, 0), WithSkipNofollow())
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	crawler.CrawlChildrenWithDepth(context.Background())
+	if fetched["https://example.com/sponsored"] != 0 || fetched["https://example.com/followed"] != 1 {
+		t.Errorf("Expected only the link without nofollow to be crawled, got %v", fetched)
+	}
+	if crawler.Skipped["https://example.com/sponsored"] != "nofollow link" {
+		t.Errorf("Expected the nofollow link to be recorded as skipped, got %v", crawler.Skipped)
+	}
+	if len(crawler.TextLinksCrawled) < 2 {
+		t.Errorf("Expected the nofollow link to still be recorded, got %v", crawler.TextLinksCrawled)
+	}
+
+	fetched = make(map[string]int)
+	crawler = New("https://example.com/", WithMaxDepth(2), WithFetcher(fakeSite(site, fetched)), WithHostLimits(2, 0))
+	crawler.Crawl(context.Background())
+	crawler.CrawlChildrenWithDepth(context.Background())
+	if fetched["https://example.com/sponsored"] != 1 {
+		t.Error("Expected nofollow links to be followed by default")
+	}
+}
+
+func TestCrawlTrackingParams(t *testing.T) {
+	site := map[string]string{
+		"https://example.com/": `<a href="/a">A</a><a href="/a?ref=home">A from home</a><a href="/a?utm_source=x">Tracked</a>`,
+	}
+	fetchedDefault := make(map[string]int)
+	withDefault := New("https://example.com/", WithMaxDepth(2), WithFetcher(fakeSite(site, fetchedDefault)), WithHostLimits(2, 0))
+	fetchedRef := make(map[string]int)
+	withRef := New("https://example.com/", WithMaxDepth(2), WithFetcher(fakeSite(site, fetchedRef)), WithHostLimits(2, 0), WithTrackingParams([]string{"ref"}))
+	for _, crawler := range []*Crawler{withDefault, withRef} {
+		if err := crawler.Crawl(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		crawler.CrawlChildrenWithDepth(context.Background())
+	}
+	if fetchedDefault["https://example.com/a"] != 1 || fetchedDefault["https://example.com/a?ref=home"] != 1 || fetchedDefault["https://example.com/a?utm_source=x"] != 0 {
+		t.Errorf("Expected the default tracking params to be stripped, got %v", fetchedDefault)
+	}
+	if fetchedRef["https://example.com/a"] != 1 || fetchedRef["https://example.com/a?ref=home"] != 0 || fetchedRef["https://example.com/a?utm_source=x"] != 1 {
+		t.Errorf("Expected only the configured params to be stripped, got %v", fetchedRef)
 	}
 }
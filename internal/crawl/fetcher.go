@@ -0,0 +1,62 @@
+package crawl
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+const defaultFetchTimeout = 30 * time.Second
+
+// Fetcher performs the http requests of a crawl, it can be swapped for a
+// caching, recording or mocked implementation.
+type Fetcher interface {
+	Fetch(req *http.Request) (*http.Response, error)
+}
+
+type FetcherFunc func(req *http.Request) (*http.Response, error)
+
+func (f FetcherFunc) Fetch(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+type HTTPFetcher struct {
+	Client *http.Client
+}
+
+// NewTransport builds a pooled transport, when guard is set every connection
+// goes through it and the proxy from the environment is ignored, since a
+// proxy would dial the internal hosts on our behalf.
+func NewTransport(guard *IPGuard) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	proxy := http.ProxyFromEnvironment
+	if guard != nil {
+		dialer.Control = guard.Control
+		proxy = nil
+	}
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   defaultMaxPerHost,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+}
+
+func NewHTTPFetcher(timeout time.Duration, transport http.RoundTripper) *HTTPFetcher {
+	return &HTTPFetcher{Client: &http.Client{Timeout: timeout, Transport: transport, CheckRedirect: noFollowRedirects}}
+}
+
+func (f *HTTPFetcher) Fetch(req *http.Request) (*http.Response, error) {
+	return f.Client.Do(req)
+}
+
+// sharedTransport is reused by every crawler built with New so connections
+// are pooled across crawls.
+var sharedTransport = NewTransport(NewIPGuard(nil))
@@ -0,0 +1,96 @@
+package crawl
+
+import (
+	"net/url"
+	"sync"
+
+	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
+)
+
+type frontierEntry struct {
+	URL   string
+	Depth int
+}
+
+// frontier is the queue of urls waiting to be crawled plus the set of every
+// url already seen in this run, keyed by its normalized form.
+type frontier struct {
+	mu             sync.Mutex
+	queue          []frontierEntry
+	visited        map[string]int
+	trackingParams []string
+}
+
+func newFrontier(trackingParams []string) *frontier {
+	return &frontier{visited: make(map[string]int), trackingParams: trackingParams}
+}
+
+func normalizeKey(link string, trackingParams []string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil || !u.IsAbs() || u.Host == "" {
+		return "", false
+	}
+	normalized, err := validate.NormalizeURL(u, trackingParams)
+	if err != nil {
+		return "", false
+	}
+	return normalized.String(), true
+}
+
+// add queues the link at the given depth, it returns false when the link was
+// already seen or can't be crawled.
+func (f *frontier) add(link string, depth int) bool {
+	key, ok := normalizeKey(link, f.trackingParams)
+	if !ok {
+		return false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, seen := f.visited[key]; seen {
+		return false
+	}
+	f.visited[key] = depth
+	f.queue = append(f.queue, frontierEntry{URL: link, Depth: depth})
+	return true
+}
+
+// markVisited records a link that was crawled outside the frontier, like
+// the seed url.
+func (f *frontier) markVisited(link string, depth int) {
+	key, ok := normalizeKey(link, f.trackingParams)
+	if !ok {
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, seen := f.visited[key]; !seen {
+		f.visited[key] = depth
+	}
+}
+
+func (f *frontier) popDepth(depth int) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var links []string
+	remaining := f.queue[:0]
+	for _, entry := range f.queue {
+		if entry.Depth == depth {
+			links = append(links, entry.URL)
+		} else {
+			remaining = append(remaining, entry)
+		}
+	}
+	f.queue = remaining
+	return links
+}
+
+func (f *frontier) depthOf(link string) (int, bool) {
+	key, ok := normalizeKey(link, f.trackingParams)
+	if !ok {
+		return 0, false
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	depth, seen := f.visited[key]
+	return depth, seen
+}
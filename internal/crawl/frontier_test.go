@@ -0,0 +1,33 @@
+package crawl
+
+import (
+	"testing"
+)
+
+func TestFrontierDeduplication(t *testing.T) {
+	f := newFrontier(nil)
+	f.markVisited("https://example.com", 0)
+	if f.add("https://EXAMPLE.com/", 1) {
+		t.Error("Expected the seed to be already visited")
+	}
+	if !f.add("https://example.com/docs", 1) {
+		t.Error("Expected a new link to be queued")
+	}
+	if f.add("https://example.com/docs#intro", 2) {
+		t.Error("Expected a link differing only by fragment to be deduplicated")
+	}
+	if f.add("not a url", 1) {
+		t.Error("Expected an invalid link to be rejected")
+	}
+	f.add("https://example.com/blog", 2)
+	links := f.popDepth(1)
+	if len(links) != 1 || links[0] != "https://example.com/docs" {
+		t.Errorf("Expected only the depth 1 link, got %v", links)
+	}
+	if len(f.popDepth(1)) != 0 {
+		t.Error("Expected depth 1 to be empty after popping it")
+	}
+	if depth, ok := f.depthOf("https://example.com/blog"); !ok || depth != 2 {
+		t.Errorf("Expected the blog link to be found at depth 2, got %d %v", depth, ok)
+	}
+}
@@ -0,0 +1,113 @@
+package crawl
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrorCategory classifies the outcome of every url the crawl came across.
+type ErrorCategory string
+
+const (
+	CategorySuccess ErrorCategory = "success"
+	CategoryHTTP    ErrorCategory = "http_error"
+	CategoryNetwork ErrorCategory = "network_error"
+	CategoryRobots  ErrorCategory = "robots_blocked"
+	CategoryScope   ErrorCategory = "out_of_scope"
+	CategoryParse   ErrorCategory = "parse_error"
+	// CategoryCanceled are urls the crawl stopped fetching when it was
+	// interrupted or ran out of time, they say nothing about the url.
+	CategoryCanceled ErrorCategory = "canceled"
+)
+
+var ErrParse = errors.New("couldn't parse the page")
+
+// ErrCanceled wraps the error of a url whose fetch was cut short because the
+// crawl's context ended.
+var ErrCanceled = errors.New("crawl stopped before the url was fetched")
+
+// Failed reports if the category is an actual failure, urls left out on
+// purpose by robots.txt or the scope are not.
+func (c ErrorCategory) Failed() bool {
+	return c == CategoryHTTP || c == CategoryNetwork || c == CategoryParse
+}
+
+// Categorize returns the category of a crawl result.
+func Categorize(result Result) ErrorCategory {
+	var skipErr *SkipError
+	switch {
+	case errors.Is(result.Error, ErrCanceled):
+		return CategoryCanceled
+	case errors.As(result.Error, &skipErr):
+		if skipErr.Category != "" {
+			return skipErr.Category
+		}
+		return CategoryScope
+	case errors.Is(result.Error, ErrParse):
+		return CategoryParse
+	case errors.Is(result.Error, ErrRedirectLoop), errors.Is(result.Error, ErrTooManyRedirects), errors.Is(result.Error, ErrRedirectNoLocation):
+		return CategoryHTTP
+	case result.Error != nil:
+		return CategoryNetwork
+	case result.Status >= 400:
+		return CategoryHTTP
+	}
+	return CategorySuccess
+}
+
+// FetchError is a url that failed during the crawl.
+type FetchError struct {
+	URL      string
+	Depth    int
+	Category ErrorCategory
+	Status   int
+	Err      error
+}
+
+func newFetchError(result Result) *FetchError {
+	return &FetchError{URL: result.URL, Depth: result.Depth, Category: result.Category, Status: result.Status, Err: result.Error}
+}
+
+func (e *FetchError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %s: %v", e.URL, e.Category, e.Err)
+	}
+	return fmt.Sprintf("%s: %s: status %d", e.URL, e.Category, e.Status)
+}
+
+func (e *FetchError) Unwrap() error {
+	return e.Err
+}
+
+// CrawlErrors is returned when some pages of a crawl failed, the rest of the
+// crawl was still completed.
+type CrawlErrors struct {
+	Failures []*FetchError
+}
+
+func (e *CrawlErrors) Error() string {
+	counts := make(map[ErrorCategory]int)
+	for _, failure := range e.Failures {
+		counts[failure.Category]++
+	}
+	return fmt.Sprintf("%d pages failed: %s", len(e.Failures), summarize(counts))
+}
+
+func (e *CrawlErrors) Unwrap() []error {
+	errs := make([]error, len(e.Failures))
+	for i, failure := range e.Failures {
+		errs[i] = failure
+	}
+	return errs
+}
+
+func summarize(counts map[ErrorCategory]int) string {
+	var parts []string
+	for category, count := range counts {
+		parts = append(parts, fmt.Sprintf("%s %d", category, count))
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
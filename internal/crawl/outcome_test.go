@@ -0,0 +1,92 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func TestCategorize(t *testing.T) {
+	testCases := []struct {
+		name     string
+		result   Result
+		expected ErrorCategory
+	}{
+		{name: "Success", result: Result{Status: http.StatusOK}, expected: CategorySuccess},
+		{name: "Http error", result: Result{Status: http.StatusNotFound}, expected: CategoryHTTP},
+		{name: "Redirect loop", result: Result{Status: http.StatusFound, Error: ErrRedirectLoop}, expected: CategoryHTTP},
+		{name: "Network error", result: Result{Error: errors.New("connection refused")}, expected: CategoryNetwork},
+		{name: "Robots", result: Result{Error: &SkipError{Category: CategoryRobots}}, expected: CategoryRobots},
+		{name: "Scope", result: Result{Error: &SkipError{Reason: "out of scope"}}, expected: CategoryScope},
+		{name: "Parse", result: Result{Status: http.StatusOK, Error: fmt.Errorf("%w: bad html", ErrParse)}, expected: CategoryParse},
+		{name: "Canceled", result: Result{Error: fmt.Errorf("%w: %w", ErrCanceled, context.Canceled)}, expected: CategoryCanceled},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if category := Categorize(tc.result); category != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, category)
+			}
+		})
+	}
+}
+
+func TestCrawlOutcomes(t *testing.T) {
+	site := map[string]string{
+		"https://example.com/robots.txt": "User-agent: *\nDisallow: /private",
+		"https://example.com/":           `<a href="/a">A</a><a href="/private">Private</a><a href="/missing">Missing</a><a href="https://other.com/">Other</a>`,
+		"https://example.com/a":          ``,
+	}
+	crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(fakeSite(site, make(map[string]int))), WithHostLimits(2, 0),
+		WithScopeRules(ScopeRules{Mode: ScopeHost}), WithStreamOnly())
+	categories := make(map[string]ErrorCategory)
+	for page := range crawler.Stream(context.Background()) {
+		categories[page.URL] = page.Category
+	}
+	var crawlErrs *CrawlErrors
+	if !errors.As(crawler.Err(), &crawlErrs) || len(crawlErrs.Failures) != 1 || crawlErrs.Failures[0].Status != http.StatusNotFound {
+		t.Fatalf("Expected the missing page to be reported, got %v", crawler.Err())
+	}
+	expected := map[string]ErrorCategory{
+		"https://example.com/":        CategorySuccess,
+		"https://example.com/a":       CategorySuccess,
+		"https://example.com/private": CategoryRobots,
+		"https://example.com/missing": CategoryHTTP,
+		"https://other.com/":          CategoryScope,
+	}
+	for link, category := range expected {
+		if categories[link] != category {
+			t.Errorf("Expected %s to be %s, got %q", link, category, categories[link])
+		}
+	}
+	if crawler.Outcomes[CategorySuccess] != 2 || crawler.Outcomes[CategoryScope] != 1 {
+		t.Errorf("Expected the outcomes to be counted, got %v", crawler.Summary())
+	}
+}
+
+func TestCrawlCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	site := fakeSite(map[string]string{"https://example.com/": `<a href="/slow">Slow</a>`}, make(map[string]int))
+	fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Path != "/slow" {
+			return site.Fetch(req)
+		}
+		cancel()
+		<-req.Context().Done()
+		return nil, req.Context().Err()
+	})
+	crawler := New("https://example.com/", WithFetcher(fetcher), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	result, _ := crawler.crawlLink(ctx, "https://example.com/slow", 1)
+	crawler.recordPage(ctx, result)
+	if category := crawler.Pages[1].Category; category != CategoryCanceled {
+		t.Errorf("Expected the interrupted fetch to be canceled, got %q", category)
+	}
+	if len(crawler.failures) != 0 {
+		t.Errorf("Expected a canceled fetch not to be reported as a failure, got %v", crawler.failures)
+	}
+}
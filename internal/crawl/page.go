@@ -0,0 +1,133 @@
+package crawl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxRefreshDelay is the longest a meta refresh can wait and still be
+// followed as a redirect, longer ones are timers like a session logout.
+const maxRefreshDelay = time.Second
+
+// PageInfo is what the crawler records about an html page besides its links.
+type PageInfo struct {
+	Title       string
+	Description string
+	Keywords    string
+	Robots      string
+	Lang        string
+	Canonical   string
+	// Refresh is the url a <meta http-equiv="refresh"> sends the page to
+	// after RefreshDelay.
+	Refresh      string
+	RefreshDelay time.Duration
+	Headings     []Heading
+	WordCount    int
+	// Text is the readable content of the page, one block per line, without
+	// its navigation, footer and other boilerplate.
+	Text string
+	// Assets are the images, scripts, stylesheets, frames and media the
+	// page loads.
+	Assets []Asset
+	// meta has the content of every <meta name> tag, repeated names are
+	// joined with commas.
+	meta map[string]string
+}
+
+type Heading struct {
+	Level int
+	Text  string
+}
+
+func (p PageInfo) String() string {
+	var b strings.Builder
+	if p.Title != "" {
+		b.WriteString(fmt.Sprintf("\ntitle: %s", p.Title))
+	}
+	if p.Description != "" {
+		b.WriteString(fmt.Sprintf("\ndescription: %s", p.Description))
+	}
+	if p.Lang != "" {
+		b.WriteString(fmt.Sprintf("\nlang: %s", p.Lang))
+	}
+	if p.Canonical != "" {
+		b.WriteString(fmt.Sprintf("\ncanonical: %s", p.Canonical))
+	}
+	for _, heading := range p.Headings {
+		b.WriteString(fmt.Sprintf("\nh%d: %s", heading.Level, heading.Text))
+	}
+	if p.WordCount > 0 {
+		b.WriteString(fmt.Sprintf("\nwords: %d", p.WordCount))
+	}
+	return b.String()
+}
+
+// refreshURL returns the url and delay of a meta refresh content like
+// "0; url='/next'", ok is false when it only reloads the page or the delay
+// isn't a number.
+func refreshURL(content string) (string, time.Duration, bool) {
+	seconds, target, found := strings.Cut(content, ";")
+	if !found {
+		seconds, target, found = strings.Cut(content, ",")
+	}
+	target = strings.TrimSpace(target)
+	if !found || target == "" {
+		return "", 0, false
+	}
+	delay, err := strconv.ParseFloat(strings.TrimSpace(seconds), 64)
+	if err != nil || delay < 0 {
+		return "", 0, false
+	}
+	if len(target) > 3 && strings.EqualFold(target[:3], "url") {
+		if rest := strings.TrimSpace(target[3:]); strings.HasPrefix(rest, "=") {
+			target = strings.TrimSpace(rest[1:])
+		}
+	}
+	target = strings.Trim(target, `'"`)
+	return target, time.Duration(delay * float64(time.Second)), target != ""
+}
+
+// headingLevel returns the level of h1 to h3, headings below that are
+// treated as text.
+func headingLevel(tag string) int {
+	switch tag {
+	case "h1":
+		return 1
+	case "h2":
+		return 2
+	case "h3":
+		return 3
+	}
+	return 0
+}
+
+// hiddenText are the elements whose text is never shown on the page.
+var hiddenText = map[string]bool{
+	"script": true, "style": true, "noscript": true, "template": true, "title": true,
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// anchorText collects the text of a link while its element is open.
+type anchorText struct {
+	link      int
+	content   strings.Builder
+	ariaLabel string
+	title     string
+	alt       strings.Builder
+}
+
+// text returns the text inside the link, or its aria-label, title or the alt
+// of its images when it has none.
+func (a *anchorText) text() string {
+	for _, text := range []string{a.content.String(), a.ariaLabel, a.title, a.alt.String()} {
+		if text = collapseSpaces(text); text != "" {
+			return text
+		}
+	}
+	return ""
+}
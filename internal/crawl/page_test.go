@@ -0,0 +1,248 @@
+package crawl
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
+	"golang.org/x/net/html"
+)
+
+func TestRetrieveUrlDataPageInfo(t *testing.T) {
+	page := `<!DOCTYPE html><html lang="en-GB"><head>
+		<title> Go   crawler </title>
+		<meta name="Description" content="A small crawler">
+		<meta name="keywords" content="go, crawler">
+		<meta name="robots" content="noindex">
+		<link rel="canonical" href="/docs/?utm_source=x">
+		<style>body { color: red }</style>
+		<script>var words = "not counted";</script>
+	</head><body>
+		<h1>Getting <em>started</em></h1>
+		<p>Read the <a href="/guide">guide</a> first.</p>
+		<h2>Install</h2>
+		<h4>Not recorded</h4>
+		<h3>Run it</h3>
+	</body></html>`
+	baseUrl, _ := url.Parse("https://example.com/docs/intro")
+	info, links, err := retrieveUrlData(baseUrl, html.NewTokenizer(strings.NewReader(page)), 1, validate.DefaultTrackingParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Title != "Go crawler" || info.Description != "A small crawler" || info.Keywords != "go, crawler" || info.Robots != "noindex" {
+		t.Errorf("Unexpected meta data %+v", info)
+	}
+	if info.Lang != "en-GB" || info.Canonical != "https://example.com/docs" {
+		t.Errorf("Expected the language and canonical url, got %q %q", info.Lang, info.Canonical)
+	}
+	expected := []Heading{{1, "Getting started"}, {2, "Install"}, {3, "Run it"}}
+	if len(info.Headings) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, info.Headings)
+	}
+	for i, heading := range expected {
+		if info.Headings[i] != heading {
+			t.Errorf("Expected %v, got %v", heading, info.Headings[i])
+		}
+	}
+	if info.WordCount != 11 {
+		t.Errorf("Expected 11 visible words, got %d", info.WordCount)
+	}
+	if len(links) != 1 || links[0].Text != "guide" {
+		t.Errorf("Expected the link text to still be recorded, got %v", links)
+	}
+}
+
+func TestRetrieveUrlDataAnchorText(t *testing.T) {
+	page := `<body>
+		<a href="/docs"><span>Docs</span></a>
+		<a href="/guide">  The
+			<strong>full</strong>   guide </a>
+		<a href="/home"><img src="/logo.png" alt="Home page"></a>
+		<a href="/search" aria-label="Search the site"><svg></svg></a>
+		<a href="/help" title="Get help"> </a>
+		<a href="/labelled" aria-label="Label">Visible text</a>
+		<a href="/open">Unclosed <a href="/next">Next</a>
+		<a href="/script"><script>ignored()</script>Shown</a>
+		<a href="/empty"></a>
+	</body>`
+	baseUrl, _ := url.Parse("https://example.com/")
+	_, links, err := retrieveUrlData(baseUrl, html.NewTokenizer(strings.NewReader(page)), 1, validate.DefaultTrackingParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"Docs", "The full guide", "Home page", "Search the site", "Get help", "Visible text", "Unclosed", "Next", "Shown", ""}
+	if len(links) != len(expected) {
+		t.Fatalf("Expected %d links, got %v", len(expected), links)
+	}
+	for i, text := range expected {
+		if links[i].Text != text {
+			t.Errorf("Expected link %s to have text %q, got %q", links[i].Target, text, links[i].Text)
+		}
+	}
+}
+
+func TestRetrieveUrlDataBaseAndRefresh(t *testing.T) {
+	page := `<head>
+		<base href="https://cdn.example.com/docs/">
+		<base href="https://ignored.com/">
+		<meta http-equiv="Refresh" content="0; URL='../moved'">
+		<link rel="canonical" href="intro">
+	</head><body><a href="guide" rel="nofollow sponsored">Guide</a></body>`
+	baseUrl, _ := url.Parse("https://example.com/page")
+	info, links, err := retrieveUrlData(baseUrl, html.NewTokenizer(strings.NewReader(page)), 1, validate.DefaultTrackingParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(links) != 1 || links[0].Target != "https://cdn.example.com/docs/guide" || links[0].Source != baseUrl.String() {
+		t.Fatalf("Expected links to be resolved against the first base href, got %v", links)
+	}
+	if len(links[0].Rel) != 2 || links[0].Rel[0] != "nofollow" || links[0].Rel[1] != "sponsored" {
+		t.Errorf("Expected the rel values to be recorded, got %v", links[0].Rel)
+	}
+	if info.Refresh != "https://cdn.example.com/moved" || info.Canonical != "https://cdn.example.com/docs/intro" {
+		t.Errorf("Expected the refresh and canonical urls to use the base href, got %q %q", info.Refresh, info.Canonical)
+	}
+}
+
+func TestRefreshURL(t *testing.T) {
+	testCases := []struct {
+		content  string
+		expected string
+		delay    time.Duration
+		ok       bool
+	}{
+		{content: "0; url=/next", expected: "/next", ok: true},
+		{content: "5;URL = 'https://example.com/'", expected: "https://example.com/", delay: 5 * time.Second, ok: true},
+		{content: `3, "/comma"`, expected: "/comma", delay: 3 * time.Second, ok: true},
+		{content: "0; /bare", expected: "/bare", ok: true},
+		{content: "0.5; url=/half", expected: "/half", delay: 500 * time.Millisecond, ok: true},
+		{content: "30", ok: false},
+		{content: "0; url=", ok: false},
+		{content: "soon; url=/next", ok: false},
+	}
+	for _, tc := range testCases {
+		target, delay, ok := refreshURL(tc.content)
+		if target != tc.expected || delay != tc.delay || ok != tc.ok {
+			t.Errorf("Expected %q %v %v for %q, got %q %v %v", tc.expected, tc.delay, tc.ok, tc.content, target, delay, ok)
+		}
+	}
+}
+
+func TestRetrieveUrlDataAssets(t *testing.T) {
+	page := `<head>
+		<base href="https://cdn.example.com/static/">
+		<link rel="stylesheet" href="site.css">
+		<link rel="shortcut icon" href="/favicon.ico">
+		<link rel="preload" href="font.woff2" as="font">
+		<link rel="alternate" href="/feed.xml">
+		<script src="app.js"></script>
+		<script>inline()</script>
+	</head><body>
+		<img src="logo.png" srcset="logo.png 1x, logo@2x.png 2x">
+		<img src="data:image/png;base64,AAAA">
+		<picture><source srcset="hero.webp"><img src="hero.jpg"></picture>
+		<video src="intro.mp4" poster="intro.jpg"><source src="intro.webm"></video>
+		<audio><source src="theme.ogg"></audio>
+		<iframe src="https://maps.example.org/embed"></iframe>
+	</body>`
+	baseUrl, _ := url.Parse("https://example.com/")
+	info, _, err := retrieveUrlData(baseUrl, html.NewTokenizer(strings.NewReader(page)), 0, validate.DefaultTrackingParams())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Asset{
+		{URL: "https://cdn.example.com/static/site.css", Kind: AssetStylesheet},
+		{URL: "https://cdn.example.com/favicon.ico", Kind: AssetIcon},
+		{URL: "https://cdn.example.com/static/font.woff2", Kind: AssetFont},
+		{URL: "https://cdn.example.com/static/app.js", Kind: AssetScript},
+		{URL: "https://cdn.example.com/static/logo.png", Kind: AssetImage},
+		{URL: "https://cdn.example.com/static/logo@2x.png", Kind: AssetImage},
+		{URL: "https://cdn.example.com/static/hero.webp", Kind: AssetImage},
+		{URL: "https://cdn.example.com/static/hero.jpg", Kind: AssetImage},
+		{URL: "https://cdn.example.com/static/intro.mp4", Kind: AssetVideo},
+		{URL: "https://cdn.example.com/static/intro.jpg", Kind: AssetImage},
+		{URL: "https://cdn.example.com/static/intro.webm", Kind: AssetVideo},
+		{URL: "https://cdn.example.com/static/theme.ogg", Kind: AssetAudio},
+		{URL: "https://maps.example.org/embed", Kind: AssetFrame},
+	}
+	if len(info.Assets) != len(expected) {
+		t.Fatalf("Expected %d assets, got %v", len(expected), info.Assets)
+	}
+	for i, asset := range expected {
+		asset.Source = baseUrl.String()
+		got := info.Assets[i]
+		got.href = ""
+		if got != asset {
+			t.Errorf("Expected %v, got %v", asset, got)
+		}
+	}
+}
+
+func TestRetrieveUrlDataText(t *testing.T) {
+	cases := []struct {
+		name string
+		page string
+		want string
+	}{
+		{
+			name: "main content",
+			page: `<body>
+				<header><a href="/">Home</a> Site name</header>
+				<nav><ul><li><a href="/docs">Docs</a></li><li><a href="/blog">Blog</a></li></ul></nav>
+				<div class="cookie-banner">We use cookies</div>
+				<p>Outside of the article</p>
+				<main><article>
+					<header><h1>Release <em>notes</em></h1><span>by the team</span></header>
+					<p>The crawler now   stores the text of every page.</p>
+					<div id="related-posts"><a href="/a">Older post</a></div>
+					<div role="main"><div><p>Nested content</p></div><p>is kept</p></div>
+					<p>See the <a href="/docs">docs</a> for the details.</p>
+					<section class="comments"><p>First!</p></section>
+				</article></main>
+				<aside>Sidebar text</aside>
+				<footer>Copyright</footer>
+				<script>var hidden = true;</script>
+			</body>`,
+			want: "Release notes\nby the team\nThe crawler now stores the text of every page.\nNested content\nis kept\nSee the docs for the details.",
+		},
+		{
+			name: "no main element",
+			page: `<body>
+				<nav><a href="/docs">Docs</a></nav>
+				<div class="content"><p>First paragraph.</p><p>Second one<br>on two lines.</p></div>
+				<div><a href="/a">Link one</a> <a href="/b">Link two</a> and more</div>
+				<div class="site-footer">Contact us</div>
+			</body>`,
+			want: "First paragraph.\nSecond one\non two lines.",
+		},
+		{
+			name: "page level class names",
+			page: `<body class="no-sidebar"><main class="has-comments"><p>Kept text</p>
+				<div class="comment-form-wrapper">Shown</div>
+				<div class="widget sidebar">Hidden</div></main></body>`,
+			want: "Kept text\nShown",
+		},
+		{
+			name: "page wrapped in a form",
+			page: `<body><form id="form1" method="post">
+				<form class="search-form"><label>Search the site</label><input name="q"></form>
+				<div><h1>Title</h1><p>Main body of the page.</p></div>
+			</form></body>`,
+			want: "Title\nMain body of the page.",
+		},
+	}
+	baseUrl, _ := url.Parse("https://example.com/")
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			info, _, err := retrieveUrlData(baseUrl, html.NewTokenizer(strings.NewReader(tc.page)), 0, validate.DefaultTrackingParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Text != tc.want {
+				t.Errorf("Expected %q, got %q", tc.want, info.Text)
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package crawl
+
+import (
+	"errors"
+	"net/http"
+)
+
+const defaultMaxRedirects = 10
+
+var (
+	ErrRedirectLoop       = errors.New("redirect loop")
+	ErrTooManyRedirects   = errors.New("too many redirects")
+	ErrRedirectNoLocation = errors.New("redirect without a valid location")
+)
+
+// Redirect is one hop of a redirect chain, the url that answered and the
+// redirect status it answered with.
+type Redirect struct {
+	URL    string
+	Status int
+}
+
+func isRedirect(status int) bool {
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return true
+	}
+	return false
+}
+
+// noFollowRedirects makes http.Client hand every redirect back to the
+// crawler, which follows them itself to record the chain.
+func noFollowRedirects(req *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
+}
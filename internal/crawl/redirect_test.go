@@ -0,0 +1,149 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func redirectSite(redirects map[string]string, pages map[string]string) Fetcher {
+	fetched := make(map[string]int)
+	site := fakeSite(pages, fetched)
+	return FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		if location, ok := redirects[req.URL.String()]; ok {
+			return &http.Response{
+				StatusCode: http.StatusMovedPermanently,
+				Header:     http.Header{"Location": {location}},
+				Body:       io.NopCloser(strings.NewReader("")),
+				Request:    req,
+			}, nil
+		}
+		return site.Fetch(req)
+	})
+}
+
+func TestCrawlFollowsRedirects(t *testing.T) {
+	redirects := map[string]string{
+		"https://example.com/":      "/start",
+		"https://example.com/start": "https://www.example.com/home",
+	}
+	pages := map[string]string{"https://www.example.com/home": `<a href="/a">A</a>`}
+	crawler := New("https://example.com/", WithFetcher(redirectSite(redirects, pages)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if crawler.URL != "https://www.example.com/home" {
+		t.Errorf("Expected the crawler url to be the final url, got %s", crawler.URL)
+	}
+	page := crawler.Pages[0]
+	expected := []Redirect{{URL: "https://example.com/", Status: 301}, {URL: "https://example.com/start", Status: 301}}
+	if len(page.Redirects) != len(expected) {
+		t.Fatalf("Expected %d hops, got %v", len(expected), page.Redirects)
+	}
+	for i, hop := range expected {
+		if page.Redirects[i] != hop {
+			t.Errorf("Expected hop %d to be %v, got %v", i, hop, page.Redirects[i])
+		}
+	}
+	if page.Status != http.StatusOK || len(page.InfoCrawled) != 1 || page.InfoCrawled[0].Target != "https://www.example.com/a" {
+		t.Errorf("Expected the links of the final page resolved against it, got %+v", page)
+	}
+}
+
+func TestCrawlBrokenRedirects(t *testing.T) {
+	testCases := []struct {
+		name      string
+		redirects map[string]string
+		opts      []Option
+		expected  error
+	}{
+		{name: "Loop", redirects: map[string]string{"https://example.com/": "/a", "https://example.com/a": "/"}, expected: ErrRedirectLoop},
+		{name: "Too many", redirects: map[string]string{"https://example.com/": "/a", "https://example.com/a": "/b", "https://example.com/b": "/c"}, opts: []Option{WithMaxRedirects(1)}, expected: ErrTooManyRedirects},
+		{name: "No location", redirects: map[string]string{"https://example.com/": ""}, expected: ErrRedirectNoLocation},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			opts := append([]Option{WithFetcher(redirectSite(tc.redirects, nil)), WithHostLimits(2, 0)}, tc.opts...)
+			crawler := New("https://example.com/", opts...)
+			err := crawler.Crawl(context.Background())
+			if !errors.Is(err, tc.expected) {
+				t.Fatalf("Expected %v, got %v", tc.expected, err)
+			}
+			if len(crawler.Pages) != 1 || len(crawler.Pages[0].Redirects) == 0 {
+				t.Errorf("Expected the broken chain to be recorded, got %+v", crawler.Pages)
+			}
+		})
+	}
+}
+
+func TestCrawlFollowsMetaRefresh(t *testing.T) {
+	pages := map[string]string{
+		"https://example.com/":        `<meta http-equiv="refresh" content="0; url=/new"><a href="/old-link">Old</a>`,
+		"https://example.com/new":     `<meta http-equiv="refresh" content="60"><a href="/a">A</a>`,
+		"https://example.com/loop":    `<meta http-equiv="refresh" content="0; url=/loop2">`,
+		"https://example.com/loop2":   `<meta http-equiv="refresh" content="0; url=/loop">`,
+		"https://example.com/account": `<meta http-equiv="refresh" content="1800; url=/logout"><a href="/a">A</a>`,
+	}
+	crawler := New("https://example.com/", WithFetcher(redirectSite(nil, pages)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	page := crawler.Pages[0]
+	if crawler.URL != "https://example.com/new" || len(page.Redirects) != 1 || page.Redirects[0].Status != http.StatusOK {
+		t.Fatalf("Expected the meta refresh to be followed as a redirect, got %s %v", crawler.URL, page.Redirects)
+	}
+	if len(page.InfoCrawled) != 1 || page.InfoCrawled[0].Target != "https://example.com/a" {
+		t.Errorf("Expected only the links of the final page, got %v", page.InfoCrawled)
+	}
+
+	crawler = New("https://example.com/loop", WithFetcher(redirectSite(nil, pages)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); !errors.Is(err, ErrRedirectLoop) {
+		t.Fatalf("Expected a meta refresh loop to be detected, got %v", err)
+	}
+
+	crawler = New("https://example.com/account", WithFetcher(redirectSite(nil, pages)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	page = crawler.Pages[0]
+	if crawler.URL != "https://example.com/account" || len(page.Redirects) != 0 || page.Page.RefreshDelay != 30*time.Minute {
+		t.Errorf("Expected a delayed meta refresh not to be followed, got %s %v", crawler.URL, page.Redirects)
+	}
+}
+
+func TestCrawlRequestsUrlsAsLinked(t *testing.T) {
+	// servers like WordPress redirect a directory without its trailing slash
+	redirects := map[string]string{
+		"https://example.com/about": "https://example.com/about/",
+		"https://example.com/team":  "https://example.com/team/",
+	}
+	pages := map[string]string{
+		"https://example.com/about/":           `<a href="/team/#people">Team</a><a href="/utm?utm_source=x">Tracked</a>`,
+		"https://example.com/team/":            `<p>Team</p>`,
+		"https://example.com/utm?utm_source=x": `<p>Tracked</p>`,
+	}
+	crawler := New("https://example.com/about/", WithMaxDepth(2), WithFetcher(redirectSite(redirects, pages)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatalf("Expected the seed to be requested with its trailing slash, got %v", err)
+	}
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); err != nil {
+		t.Fatalf("Expected the links to be requested as linked, got %v", err)
+	}
+	if crawler.URL != "https://example.com/about" {
+		t.Errorf("Expected the crawler url to be the normalized one, got %s", crawler.URL)
+	}
+	crawled := make(map[string]bool)
+	for _, page := range crawler.Pages {
+		crawled[page.URL] = true
+		if page.Status != http.StatusOK || len(page.Redirects) != 0 {
+			t.Errorf("Expected %s to be fetched without redirects, got %d %v", page.URL, page.Status, page.Redirects)
+		}
+	}
+	if len(crawled) != 3 || !crawled["https://example.com/team"] || !crawled["https://example.com/utm"] {
+		t.Errorf("Expected the pages to be told apart by their normalized url, got %v", crawled)
+	}
+}
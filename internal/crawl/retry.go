@@ -0,0 +1,121 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"slices"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultRetryBase   = 500 * time.Millisecond
+	defaultRetryMax    = 10 * time.Second
+)
+
+var defaultRetryStatus = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// RetryPolicy decides how often a request that failed for a transient reason
+// is sent again. The wait doubles on every attempt starting at BaseDelay, up
+// to MaxDelay, and a random jitter keeps workers from retrying in lockstep.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	RetryStatus []int
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: defaultMaxAttempts,
+		BaseDelay:   defaultRetryBase,
+		MaxDelay:    defaultRetryMax,
+		RetryStatus: defaultRetryStatus,
+	}
+}
+
+// retryableError reports if a fetch error is worth another attempt, timeouts
+// and dropped connections are, anything the crawler refused itself isn't.
+// Only the crawl's own ctx ending stops the retries, a client timeout also
+// reports context.DeadlineExceeded.
+func retryableError(ctx context.Context, err error) bool {
+	var blocked *BlockedAddressError
+	if ctx.Err() != nil || errors.As(err, &blocked) {
+		return false
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) && dnsErr.IsTemporary {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// wait returns how long to wait before the given attempt is retried, a
+// Retry-After further away than MaxDelay gives up on the request.
+func (p RetryPolicy) wait(attempt int, resp *http.Response, now time.Time) (time.Duration, bool) {
+	delay := p.BaseDelay << (attempt - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+	if delay > 0 {
+		delay = delay/2 + rand.N(delay/2+1)
+	}
+	if resp != nil {
+		if until, ok := parseRetryAfter(resp.Header.Get("Retry-After"), now); ok {
+			retryAfter := until.Sub(now)
+			if retryAfter > p.MaxDelay {
+				return 0, false
+			}
+			delay = max(delay, retryAfter)
+		}
+	}
+	return delay, true
+}
+
+// retry reports if a request answered with resp or err, that already took
+// attempt tries, should be sent again and how long to wait first.
+func (p RetryPolicy) retry(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxAttempts {
+		return 0, false
+	}
+	if err != nil && !retryableError(ctx, err) {
+		return 0, false
+	}
+	if err == nil && !slices.Contains(p.RetryStatus, resp.StatusCode) {
+		return 0, false
+	}
+	return p.wait(attempt, resp, time.Now())
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
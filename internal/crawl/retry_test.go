@@ -0,0 +1,124 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
+)
+
+func TestCrawlRetries(t *testing.T) {
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	flaky := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		attempts[req.URL.Path]++
+		attempt := attempts[req.URL.Path]
+		mu.Unlock()
+		status := http.StatusOK
+		switch {
+		case req.URL.Path == "/" && attempt == 1:
+			return nil, io.ErrUnexpectedEOF
+		case req.URL.Path == "/" && attempt == 2:
+			status = http.StatusBadGateway
+		case req.URL.Path == "/down":
+			status = http.StatusServiceUnavailable
+		case req.URL.Path == "/missing":
+			status = http.StatusNotFound
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Retry-After": {"0"}},
+			Body:       io.NopCloser(strings.NewReader(`<a href="/down">Down</a><a href="/missing">Missing</a>`)),
+			Request:    req,
+		}, nil
+	})
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, RetryStatus: defaultRetryStatus}
+	crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(flaky), WithHostLimits(2, 0), WithRetryPolicy(policy))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	var crawlErrs *CrawlErrors
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); !errors.As(err, &crawlErrs) || len(crawlErrs.Failures) != 2 {
+		t.Fatalf("Expected the two failed pages to be reported, got %v", err)
+	}
+	expected := map[string]struct{ status, attempts int }{
+		"https://example.com/":        {http.StatusOK, 3},
+		"https://example.com/down":    {http.StatusServiceUnavailable, 3},
+		"https://example.com/missing": {http.StatusNotFound, 1},
+	}
+	for _, page := range crawler.Pages {
+		want, ok := expected[page.URL]
+		if !ok {
+			continue
+		}
+		if page.Status != want.status || page.Attempts != want.attempts {
+			t.Errorf("Expected %s to end with %d after %d attempts, got %d after %d", page.URL, want.status, want.attempts, page.Status, page.Attempts)
+		}
+	}
+}
+
+func TestCrawlRetriesTimeouts(t *testing.T) {
+	var mu sync.Mutex
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		mu.Lock()
+		attempts++
+		attempt := attempts
+		mu.Unlock()
+		if attempt == 1 {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+			return
+		}
+		w.Write([]byte(`<p>Home</p>`))
+	}))
+	defer server.Close()
+	loopback, _ := ParseNetworks("127.0.0.0/8,::1")
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, RetryStatus: defaultRetryStatus}
+	crawler := New(server.URL, WithSchemePolicy(validate.AllowHTTP), WithAllowedNetworks(loopback), WithHostLimits(2, 0),
+		WithFetchTimeout(100*time.Millisecond), WithRetryPolicy(policy))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatalf("Expected a request that timed out to be retried, got %v", err)
+	}
+	if page := crawler.Pages[0]; page.Status != http.StatusOK || page.Attempts != 2 {
+		t.Errorf("Expected the page to be fetched on the second attempt, got %d after %d", page.Status, page.Attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, retry := policy.retry(ctx, 1, nil, context.Canceled); retry {
+		t.Error("Expected nothing to be retried once the crawl is canceled")
+	}
+}
+
+func TestRetryPolicyWait(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 5, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	for attempt, limit := range []time.Duration{100, 200, 400, 800, 1000} {
+		wait, ok := policy.wait(attempt+1, nil, time.Now())
+		if !ok || wait < limit*time.Millisecond/2 || wait > limit*time.Millisecond {
+			t.Errorf("Expected attempt %d to wait up to %v, got %v", attempt+1, limit*time.Millisecond, wait)
+		}
+	}
+	resp := &http.Response{Header: http.Header{"Retry-After": {"1"}}}
+	if wait, ok := policy.wait(1, resp, time.Now()); !ok || wait < 900*time.Millisecond {
+		t.Errorf("Expected Retry-After to be honoured, got %v", wait)
+	}
+	resp.Header.Set("Retry-After", "3600")
+	if _, ok := policy.wait(1, resp, time.Now()); ok {
+		t.Error("Expected a Retry-After past MaxDelay to give up")
+	}
+}
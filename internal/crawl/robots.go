@@ -0,0 +1,321 @@
+package crawl
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// robots.txt files bigger than this are truncated, as RFC 9309 allows.
+const maxRobotsSize = 500 * 1024
+
+// maxRobotsRedirects are the redirects followed for a robots.txt, RFC 9309
+// asks for at least five.
+const maxRobotsRedirects = 5
+
+type SkipError struct {
+	URL      string
+	Reason   string
+	Category ErrorCategory
+}
+
+func (e *SkipError) Error() string {
+	return fmt.Sprintf("skipped %s: %s", e.URL, e.Reason)
+}
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+type robotsGroup struct {
+	agents     []string
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+type RobotsRules struct {
+	groups      []*robotsGroup
+	unreachable bool
+	Sitemaps    []string
+}
+
+const robotsUnreachableReason = "robots.txt unreachable, the whole host is disallowed"
+
+func ParseRobots(r io.Reader) *RobotsRules {
+	rules := &RobotsRules{}
+	var current *robotsGroup
+	lastWasAgent := false
+	scanner := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if current == nil || !lastWasAgent {
+				current = &robotsGroup{}
+				rules.groups = append(rules.groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			lastWasAgent = true
+			continue
+		case "allow", "disallow":
+			if current != nil {
+				current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if current != nil {
+				if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+					current.crawlDelay = time.Duration(seconds * float64(time.Second))
+				}
+			}
+		case "sitemap":
+			rules.Sitemaps = append(rules.Sitemaps, value)
+		}
+		lastWasAgent = false
+	}
+	return rules
+}
+
+// group merges every group that applies to the user agent, preferring the
+// groups naming its product token over the "*" group. As RFC 9309 asks the
+// whole token is compared, ignoring case.
+func (r *RobotsRules) group(userAgent string) *robotsGroup {
+	token := userAgentToken(userAgent)
+	var best *robotsGroup
+	bestLen := -1
+	for _, g := range r.groups {
+		for _, agent := range g.agents {
+			matchLen := -1
+			if agent == "*" {
+				matchLen = 0
+			} else if agent != "" && agent == token {
+				matchLen = len(agent)
+			}
+			if matchLen < 0 {
+				continue
+			}
+			if matchLen > bestLen {
+				best = &robotsGroup{rules: append([]robotsRule(nil), g.rules...), crawlDelay: g.crawlDelay}
+				bestLen = matchLen
+			} else if matchLen == bestLen {
+				best.rules = append(best.rules, g.rules...)
+				if g.crawlDelay > best.crawlDelay {
+					best.crawlDelay = g.crawlDelay
+				}
+			}
+		}
+	}
+	return best
+}
+
+func (r *RobotsRules) Allowed(userAgent string, u *url.URL) (bool, string) {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true, ""
+	}
+	if r.unreachable {
+		return false, robotsUnreachableReason
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	g := r.group(userAgent)
+	if g == nil {
+		return true, ""
+	}
+	var match *robotsRule
+	for i, rule := range g.rules {
+		if rule.pattern == "" || !robotsPatternMatch(rule.pattern, path) {
+			continue
+		}
+		if match == nil || len(rule.pattern) > len(match.pattern) ||
+			(len(rule.pattern) == len(match.pattern) && rule.allow && !match.allow) {
+			match = &g.rules[i]
+		}
+	}
+	if match != nil && !match.allow {
+		return false, fmt.Sprintf("disallowed by robots.txt rule %q", match.pattern)
+	}
+	return true, ""
+}
+
+func (r *RobotsRules) CrawlDelay(userAgent string) time.Duration {
+	g := r.group(userAgent)
+	if g == nil {
+		return 0
+	}
+	return g.crawlDelay
+}
+
+func robotsPatternMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i, part := range parts[1:] {
+		if anchored && i == len(parts)-2 {
+			return strings.HasSuffix(path[pos:], part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx < 0 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+	return !anchored || pos == len(path)
+}
+
+var (
+	allowAllRobots    = &RobotsRules{}
+	unreachableRobots = &RobotsRules{unreachable: true}
+)
+
+// robotsCache keeps the rules of every host crawled, the first url of a host
+// fetches its robots.txt while the others wait for it. A robots.txt that
+// couldn't be reached isn't kept.
+type robotsCache struct {
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+type robotsEntry struct {
+	done  chan struct{}
+	rules *RobotsRules
+	err   error
+}
+
+func newRobotsCache() *robotsCache {
+	return &robotsCache{hosts: make(map[string]*robotsEntry)}
+}
+
+// rules returns the rules saved for key, calling fetch when no other url of
+// the host already did. err is the network error that kept the robots.txt
+// from being fetched.
+func (rc *robotsCache) rules(ctx context.Context, key string, fetch func() (*RobotsRules, error)) (*RobotsRules, error) {
+	rc.mu.Lock()
+	entry, ok := rc.hosts[key]
+	if !ok {
+		entry = &robotsEntry{done: make(chan struct{})}
+		rc.hosts[key] = entry
+	}
+	rc.mu.Unlock()
+	if ok {
+		select {
+		case <-entry.done:
+		case <-ctx.Done():
+			return unreachableRobots, ctx.Err()
+		}
+		if entry.rules == nil {
+			// the fetch was canceled before it got an answer
+			return rc.rules(ctx, key, fetch)
+		}
+		return entry.rules, entry.err
+	}
+	rules, err := fetch()
+	if ctx.Err() == nil {
+		entry.rules, entry.err = rules, err
+	}
+	// an unreachable robots.txt is asked for again by the next url of the host
+	if ctx.Err() != nil || rules.unreachable {
+		rc.mu.Lock()
+		delete(rc.hosts, key)
+		rc.mu.Unlock()
+	}
+	close(entry.done)
+	return rules, err
+}
+
+func (c *Crawler) robotsRules(ctx context.Context, u *url.URL) (*RobotsRules, error) {
+	key := u.Scheme + "://" + u.Host
+	return c.robots.rules(ctx, key, func() (*RobotsRules, error) {
+		return c.fetchRobots(ctx, key+"/robots.txt")
+	})
+}
+
+// checkRobots refuses urls disallowed by their robots.txt, a host whose
+// robots.txt couldn't be reached at all fails with its network error.
+func (c *Crawler) checkRobots(ctx context.Context, u *url.URL) error {
+	rules, err := c.robotsRules(ctx, u)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("couldn't fetch the robots.txt of %s: %w", u.Host, err)
+	}
+	if ok, reason := rules.Allowed(c.Config.UserAgent, u); !ok {
+		return &SkipError{URL: u.String(), Reason: reason, Category: CategoryRobots}
+	}
+	return nil
+}
+
+// fetchRobots follows RFC 9309: a missing robots.txt allows everything while
+// an unreachable one disallows the whole host. Redirects are followed since
+// the fetcher doesn't, past maxRobotsRedirects the file is treated as missing.
+// Every request goes through the host scheduler and the retry policy like a
+// page would, a request that got no answer returns its error.
+func (c *Crawler) fetchRobots(ctx context.Context, robotsUrl string) (*RobotsRules, error) {
+	for redirects := 0; ; redirects++ {
+		target, err := url.Parse(robotsUrl)
+		if err != nil {
+			return unreachableRobots, err
+		}
+		resp, release, err := c.send(ctx, http.MethodGet, target, CacheValidators{}, c.Config.HostDelay, &Result{})
+		var blocked *BlockedAddressError
+		if errors.As(err, &blocked) {
+			// the page fetch is refused by the same guard with a clearer reason
+			return allowAllRobots, nil
+		} else if err != nil {
+			return unreachableRobots, err
+		}
+		if !isRedirect(resp.StatusCode) {
+			defer release()
+			return readRobots(resp), nil
+		}
+		resp.Body.Close()
+		release()
+		if redirects == maxRobotsRedirects {
+			return allowAllRobots, nil
+		}
+		next, err := target.Parse(resp.Header.Get("Location"))
+		if resp.Header.Get("Location") == "" || err != nil {
+			return allowAllRobots, nil
+		}
+		robotsUrl = next.String()
+	}
+}
+
+// readRobots turns the final answer for a robots.txt into its rules.
+func readRobots(resp *http.Response) *RobotsRules {
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return ParseRobots(resp.Body)
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return allowAllRobots
+	default:
+		return unreachableRobots
+	}
+}
@@ -0,0 +1,225 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+# comment line
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search?q=*&page=
+Crawl-delay: 2
+
+User-agent: go-webcrawler
+User-agent: otherbot
+Disallow: /no-crawler
+Crawl-delay: 0.5
+
+User-agent: Bot
+Disallow: /
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+func TestRobotsAllowed(t *testing.T) {
+	rules := ParseRobots(strings.NewReader(testRobots))
+	testCases := []struct {
+		name      string
+		userAgent string
+		path      string
+		allowed   bool
+	}{
+		{name: "Root for generic bot", userAgent: "randombot", path: "/", allowed: true},
+		{name: "Disallowed prefix", userAgent: "randombot", path: "/private/data", allowed: false},
+		{name: "Longer allow wins", userAgent: "randombot", path: "/private/public/page", allowed: true},
+		{name: "Wildcard with end anchor", userAgent: "randombot", path: "/docs/file.pdf", allowed: false},
+		{name: "End anchor not matched", userAgent: "randombot", path: "/docs/file.pdf.html", allowed: true},
+		{name: "Wildcard in query", userAgent: "randombot", path: "/search?q=go&page=2", allowed: false},
+		{name: "Specific group overrides star", userAgent: DefaultUserAgent, path: "/private/data", allowed: true},
+		{name: "Specific group rule", userAgent: DefaultUserAgent + "/1.0", path: "/no-crawler/x", allowed: false},
+		{name: "Whole token matched ignoring case", userAgent: "bot/2.1", path: "/", allowed: false},
+		{name: "Robots file always allowed", userAgent: "otherbot", path: "/robots.txt", allowed: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse("https://example.com" + tc.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed, reason := rules.Allowed(tc.userAgent, u)
+			if allowed != tc.allowed {
+				t.Errorf("Expected allowed=%v for %s, got %v (%s)", tc.allowed, tc.path, allowed, reason)
+			}
+		})
+	}
+}
+
+func TestRobotsCrawlDelayAndSitemaps(t *testing.T) {
+	rules := ParseRobots(strings.NewReader(testRobots))
+	if delay := rules.CrawlDelay("randombot"); delay != 2*time.Second {
+		t.Errorf("Expected a crawl delay of 2s, got %v", delay)
+	}
+	if delay := rules.CrawlDelay(DefaultUserAgent); delay != 500*time.Millisecond {
+		t.Errorf("Expected a crawl delay of 500ms, got %v", delay)
+	}
+	if len(rules.Sitemaps) != 1 || rules.Sitemaps[0] != "https://example.com/sitemap.xml" {
+		t.Errorf("Expected one sitemap, got %v", rules.Sitemaps)
+	}
+}
+
+func TestRobotsFollowsRedirects(t *testing.T) {
+	redirects := map[string]string{
+		"https://example.com/robots.txt":     "https://www.example.com/robots.txt",
+		"https://www.example.com/robots.txt": "/robots-live.txt",
+	}
+	pages := map[string]string{
+		"https://www.example.com/robots-live.txt": "User-agent: *\nDisallow: /private",
+		"https://example.com/":                    `<a href="/private/x">Private</a>`,
+	}
+	crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(redirectSite(redirects, pages)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatalf("Expected a redirected robots.txt to be followed, got %v", err)
+	}
+	crawler.CrawlChildrenWithDepth(context.Background())
+	if reason := crawler.Skipped["https://example.com/private/x"]; !strings.Contains(reason, "/private") {
+		t.Errorf("Expected the rules of the redirected robots.txt to apply, got %v", crawler.Skipped)
+	}
+
+	redirects = map[string]string{"https://example.com/robots.txt": "/r1"}
+	for i := 1; i <= maxRobotsRedirects; i++ {
+		redirects["https://example.com/r"+strconv.Itoa(i)] = "/r" + strconv.Itoa(i+1)
+	}
+	pages = map[string]string{"https://example.com/": `<p>Home</p>`}
+	crawler = New("https://example.com/", WithFetcher(redirectSite(redirects, pages)), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatalf("Expected too many robots.txt redirects to allow the host, got %v", err)
+	}
+}
+
+func TestRobotsFetchedOncePerHost(t *testing.T) {
+	var mu sync.Mutex
+	robotsFetches, active, maxActive := 0, 0, 0
+	fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		active++
+		maxActive = max(maxActive, active)
+		if req.URL.Host == "other.com" && req.URL.Path == "/robots.txt" {
+			robotsFetches++
+		}
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		mu.Lock()
+		active--
+		mu.Unlock()
+		body := `<p>Page</p>`
+		if req.URL.Host == "example.com" && req.URL.Path == "/" {
+			body = ""
+			for i := range 8 {
+				body += `<a href="https://other.com/` + strconv.Itoa(i) + `">Other</a>`
+			}
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(fetcher), WithWorkers(8), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if robotsFetches != 1 {
+		t.Errorf("Expected robots.txt to be fetched once for the host, got %d", robotsFetches)
+	}
+	if maxActive > 2 {
+		t.Errorf("Expected robots.txt to share the limit of the host, got %d concurrent requests", maxActive)
+	}
+}
+
+func TestRobotsRetried(t *testing.T) {
+	var mu sync.Mutex
+	robotsFetches := 0
+	down := true
+	fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		status, body := http.StatusOK, `<a href="/a">A</a>`
+		if req.URL.Path == "/robots.txt" {
+			mu.Lock()
+			robotsFetches++
+			attempt, isDown := robotsFetches, down
+			mu.Unlock()
+			switch {
+			case attempt == 1:
+				return nil, context.DeadlineExceeded
+			case isDown:
+				status = http.StatusServiceUnavailable
+			default:
+				body = "User-agent: *\nDisallow: /private"
+			}
+		}
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	policy := RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond, RetryStatus: defaultRetryStatus}
+	crawler := New("https://example.com/", WithFetcher(fetcher), WithHostLimits(2, 0), WithRetryPolicy(policy))
+	if err := crawler.Crawl(context.Background()); err == nil || !strings.Contains(err.Error(), robotsUnreachableReason) {
+		t.Fatalf("Expected robots.txt to stay unreachable after its retries, got %v", err)
+	}
+	if robotsFetches != 2 {
+		t.Errorf("Expected robots.txt to be retried once, got %d fetches", robotsFetches)
+	}
+
+	mu.Lock()
+	down = false
+	mu.Unlock()
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatalf("Expected an unreachable robots.txt to be asked for again, got %v", err)
+	}
+}
+
+func TestRobotsNetworkError(t *testing.T) {
+	refused := errors.New("connection refused")
+	fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		return nil, refused
+	})
+	crawler := New("https://example.com/", WithFetcher(fetcher), WithHostLimits(2, 0))
+	err := crawler.Crawl(context.Background())
+	var skipErr *SkipError
+	if !errors.Is(err, refused) || errors.As(err, &skipErr) {
+		t.Fatalf("Expected the network error of robots.txt to be returned, got %v", err)
+	}
+	if crawler.Outcomes[CategoryNetwork] != 1 || crawler.Outcomes[CategoryRobots] != 0 {
+		t.Errorf("Expected an unreachable host to be a network error, got %v", crawler.Summary())
+	}
+}
+
+func TestRobotsSharedByHostVariants(t *testing.T) {
+	var mu sync.Mutex
+	fetched := make(map[string]int)
+	fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		fetched[req.URL.Host+req.URL.Path]++
+		mu.Unlock()
+		body := `<a href="https://EXAMPLE.com/a">A</a><a href="https://example.com:443/b">B</a>`
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body)), Request: req}, nil
+	})
+	crawler := New("https://Example.COM/", WithMaxDepth(2), WithFetcher(fetcher), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if fetched["example.com/robots.txt"] != 1 || len(fetched) != 4 {
+		t.Errorf("Expected every variant of the host to be requested as example.com, got %v", fetched)
+	}
+}
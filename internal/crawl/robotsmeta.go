@@ -0,0 +1,59 @@
+package crawl
+
+import "strings"
+
+// RobotsDirectives are the rules a page gives crawlers through a
+// <meta name="robots"> tag or an X-Robots-Tag header.
+type RobotsDirectives struct {
+	NoIndex  bool
+	NoFollow bool
+}
+
+// directivesWithColon are the X-Robots-Tag directives that carry a value,
+// anything else before a colon is the name of the bot the rules are for.
+var directivesWithColon = map[string]bool{
+	"unavailable_after": true, "max-snippet": true, "max-image-preview": true, "max-video-preview": true,
+}
+
+func (d *RobotsDirectives) add(content string) {
+	for _, directive := range strings.Split(content, ",") {
+		switch strings.ToLower(strings.TrimSpace(directive)) {
+		case "noindex":
+			d.NoIndex = true
+		case "nofollow":
+			d.NoFollow = true
+		case "none":
+			d.NoIndex = true
+			d.NoFollow = true
+		}
+	}
+}
+
+// userAgentToken is the product name of a User-Agent, like "googlebot" for
+// "Googlebot/2.1".
+func userAgentToken(userAgent string) string {
+	token, _, _ := strings.Cut(strings.TrimSpace(userAgent), "/")
+	return strings.ToLower(token)
+}
+
+// robotsDirectives merges the directives of the page meta tags and the
+// X-Robots-Tag headers that apply to userAgent, the generic ones and the
+// ones addressed to its token.
+func robotsDirectives(userAgent string, meta map[string]string, headers []string) RobotsDirectives {
+	var directives RobotsDirectives
+	token := userAgentToken(userAgent)
+	directives.add(meta["robots"])
+	if token != "robots" {
+		directives.add(meta[token])
+	}
+	for _, header := range headers {
+		bot, rules, found := strings.Cut(header, ":")
+		bot = strings.ToLower(strings.TrimSpace(bot))
+		if !found || directivesWithColon[bot] || strings.Contains(bot, ",") {
+			directives.add(header)
+		} else if bot == token {
+			directives.add(rules)
+		}
+	}
+	return directives
+}
@@ -0,0 +1,78 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+func TestRobotsDirectives(t *testing.T) {
+	testCases := []struct {
+		name      string
+		userAgent string
+		meta      map[string]string
+		headers   []string
+		expected  RobotsDirectives
+	}{
+		{name: "Meta robots", userAgent: DefaultUserAgent, meta: map[string]string{"robots": "NoIndex, follow"}, expected: RobotsDirectives{NoIndex: true}},
+		{name: "None", userAgent: DefaultUserAgent, meta: map[string]string{"robots": "none"}, expected: RobotsDirectives{NoIndex: true, NoFollow: true}},
+		{name: "Other bot meta", userAgent: DefaultUserAgent, meta: map[string]string{"googlebot": "noindex"}, expected: RobotsDirectives{}},
+		{name: "Own bot meta", userAgent: "Googlebot/2.1", meta: map[string]string{"googlebot": "noindex"}, expected: RobotsDirectives{NoIndex: true}},
+		{name: "Header", userAgent: DefaultUserAgent, headers: []string{"nofollow"}, expected: RobotsDirectives{NoFollow: true}},
+		{name: "Header for other bot", userAgent: DefaultUserAgent, headers: []string{"googlebot: noindex, nofollow"}, expected: RobotsDirectives{}},
+		{name: "Header for own bot", userAgent: "googlebot", headers: []string{"googlebot: noindex", "otherbot: nofollow"}, expected: RobotsDirectives{NoIndex: true}},
+		{name: "Header with a dated directive", userAgent: DefaultUserAgent, headers: []string{"unavailable_after: 25 Jun 2010 15:00:00 PST"}, expected: RobotsDirectives{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if directives := robotsDirectives(tc.userAgent, tc.meta, tc.headers); directives != tc.expected {
+				t.Errorf("Expected %+v, got %+v", tc.expected, directives)
+			}
+		})
+	}
+}
+
+func TestCrawlRobotsMeta(t *testing.T) {
+	site := map[string]string{
+		"https://example.com/":            `<a href="/hidden">Hidden</a><a href="/closed">Closed</a>`,
+		"https://example.com/hidden":      `<meta name="robots" content="noindex"><a href="/from-hidden">Next</a>`,
+		"https://example.com/closed":      `<meta name="robots" content="nofollow"><a href="/from-closed">Next</a>`,
+		"https://example.com/from-closed": ``,
+	}
+	for _, ignore := range []bool{false, true} {
+		fetched := make(map[string]int)
+		opts := []Option{WithMaxDepth(3), WithFetcher(fakeSite(site, fetched)), WithHostLimits(2, 0)}
+		if ignore {
+			opts = append(opts, WithIgnoreRobotsMeta())
+		}
+		crawler := New("https://example.com/", opts...)
+		if err := crawler.Crawl(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		crawler.CrawlChildrenWithDepth(context.Background())
+		if fetched["https://example.com/from-hidden"] != 1 {
+			t.Errorf("Expected the links of a noindex page to be followed, got %v", fetched)
+		}
+		if followed := fetched["https://example.com/from-closed"] == 1; followed != ignore {
+			t.Errorf("Expected the links of a nofollow page to be followed only when ignoring robots meta, got %v", fetched)
+		}
+		for _, page := range crawler.Pages {
+			if page.URL == "https://example.com/hidden" && page.Robots.NoIndex == ignore {
+				t.Errorf("Expected noindex to be reported only when honoured, got %+v", page.Robots)
+			}
+		}
+	}
+
+	header := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		resp, err := fakeSite(site, make(map[string]int)).Fetch(req)
+		resp.Header.Set("X-Robots-Tag", "noindex, nofollow")
+		return resp, err
+	})
+	crawler := New("https://example.com/", WithFetcher(header), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if robots := crawler.Pages[0].Robots; !robots.NoIndex || !robots.NoFollow {
+		t.Errorf("Expected the X-Robots-Tag header to be honoured, got %+v", robots)
+	}
+}
@@ -0,0 +1,124 @@
+package crawl
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxPerHost = 2
+	defaultHostDelay  = 250 * time.Millisecond
+)
+
+type hostState struct {
+	slots       chan struct{}
+	mu          sync.Mutex
+	nextAllowed time.Time
+}
+
+// hostScheduler keeps every host under its own concurrency limit and minimum
+// delay between requests, while different hosts are fetched in parallel.
+type hostScheduler struct {
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+func newHostScheduler() *hostScheduler {
+	return &hostScheduler{hosts: make(map[string]*hostState)}
+}
+
+func (s *hostScheduler) state(host string, maxPerHost int) *hostState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state, ok := s.hosts[host]
+	if !ok {
+		if maxPerHost < 1 {
+			maxPerHost = 1
+		}
+		state = &hostState{slots: make(chan struct{}, maxPerHost)}
+		s.hosts[host] = state
+	}
+	return state
+}
+
+func (s *hostScheduler) acquire(ctx context.Context, host string, maxPerHost int, delay time.Duration) (func(), error) {
+	state := s.state(host, maxPerHost)
+	select {
+	case state.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	release := func() { <-state.slots }
+	state.mu.Lock()
+	now := time.Now()
+	start := state.nextAllowed
+	if start.Before(now) {
+		start = now
+	}
+	state.nextAllowed = start.Add(delay)
+	state.mu.Unlock()
+	if wait := time.Until(start); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+func (s *hostScheduler) backoff(host string, until time.Time) {
+	state := s.state(host, defaultMaxPerHost)
+	state.mu.Lock()
+	if until.After(state.nextAllowed) {
+		state.nextAllowed = until
+	}
+	state.mu.Unlock()
+}
+
+func parseRetryAfter(value string, now time.Time) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return now.Add(time.Duration(seconds) * time.Second), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// interleaveByHost orders the links round-robin across hosts so the workers
+// are not all stuck waiting on the same host.
+func interleaveByHost(links []string) []string {
+	var hosts []string
+	byHost := make(map[string][]string)
+	for _, link := range links {
+		host := link
+		if u, err := url.Parse(link); err == nil {
+			host = u.Host
+		}
+		if _, ok := byHost[host]; !ok {
+			hosts = append(hosts, host)
+		}
+		byHost[host] = append(byHost[host], link)
+	}
+	ordered := make([]string, 0, len(links))
+	for len(ordered) < len(links) {
+		for _, host := range hosts {
+			if queue := byHost[host]; len(queue) > 0 {
+				ordered = append(ordered, queue[0])
+				byHost[host] = queue[1:]
+			}
+		}
+	}
+	return ordered
+}
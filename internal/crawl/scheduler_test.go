@@ -0,0 +1,73 @@
+package crawl
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSchedulerHostDelay(t *testing.T) {
+	scheduler := newHostScheduler()
+	delay := 50 * time.Millisecond
+	start := time.Now()
+	for range 3 {
+		release, err := scheduler.acquire(context.Background(), "example.com", 1, delay)
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+	if elapsed := time.Since(start); elapsed < 2*delay {
+		t.Errorf("Expected at least %v between requests to the same host, took %v", 2*delay, elapsed)
+	}
+}
+
+func TestSchedulerConcurrencyPerHost(t *testing.T) {
+	scheduler := newHostScheduler()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	active, maxActive := 0, 0
+	for range 6 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := scheduler.acquire(context.Background(), "example.com", 2, 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			active++
+			maxActive = max(maxActive, active)
+			mu.Unlock()
+			time.Sleep(10 * time.Millisecond)
+			mu.Lock()
+			active--
+			mu.Unlock()
+			release()
+		}()
+	}
+	wg.Wait()
+	if maxActive > 2 {
+		t.Errorf("Expected at most 2 concurrent requests per host, got %d", maxActive)
+	}
+}
+
+func TestSchedulerRetryAfter(t *testing.T) {
+	now := time.Now()
+	until, ok := parseRetryAfter("2", now)
+	if !ok || !until.Equal(now.Add(2*time.Second)) {
+		t.Errorf("Expected Retry-After in seconds to be parsed, got %v %v", until, ok)
+	}
+	if _, ok := parseRetryAfter("Wed, 21 Oct 2015 07:28:00 GMT", now); !ok {
+		t.Error("Expected Retry-After as an http date to be parsed")
+	}
+	scheduler := newHostScheduler()
+	scheduler.backoff("example.com", time.Now().Add(100*time.Millisecond))
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := scheduler.acquire(ctx, "example.com", 1, 0); err == nil {
+		t.Error("Expected acquire to wait for the Retry-After backoff")
+	}
+}
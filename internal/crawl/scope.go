@@ -0,0 +1,100 @@
+package crawl
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+const (
+	ScopeAll        = "all"
+	ScopeHost       = "host"
+	ScopeSubdomains = "subdomains"
+	ScopeDomain     = "domain"
+)
+
+// ScopeRules limits which links found during a crawl are followed, every
+// rule is checked against the seed url of the crawl.
+type ScopeRules struct {
+	Mode              string
+	PathPrefix        string
+	Include           []*regexp.Regexp
+	Exclude           []*regexp.Regexp
+	ExcludeExtensions []string
+}
+
+func ValidScopeMode(mode string) bool {
+	switch mode {
+	case "", ScopeAll, ScopeHost, ScopeSubdomains, ScopeDomain:
+		return true
+	}
+	return false
+}
+
+// Allows reports if u is inside the scope of a crawl started at seed, when it
+// isn't it also returns the reason.
+func (s ScopeRules) Allows(seed, u *url.URL) (bool, string) {
+	host := strings.ToLower(u.Hostname())
+	seedHost := strings.ToLower(seed.Hostname())
+	switch s.Mode {
+	case ScopeHost:
+		if host != seedHost {
+			return false, fmt.Sprintf("out of scope: host %s is not %s", host, seedHost)
+		}
+	case ScopeSubdomains:
+		if host != seedHost && !strings.HasSuffix(host, "."+seedHost) {
+			return false, fmt.Sprintf("out of scope: host %s is not a subdomain of %s", host, seedHost)
+		}
+	case ScopeDomain:
+		if registrableDomain(host) != registrableDomain(seedHost) {
+			return false, fmt.Sprintf("out of scope: host %s is not on the domain of %s", host, seedHost)
+		}
+	}
+	if !underPath(u.Path, s.PathPrefix) {
+		return false, fmt.Sprintf("out of scope: path is not under %s", s.PathPrefix)
+	}
+	if ext := strings.ToLower(path.Ext(u.Path)); ext != "" {
+		for _, excluded := range s.ExcludeExtensions {
+			if ext == "."+strings.TrimPrefix(strings.ToLower(excluded), ".") {
+				return false, fmt.Sprintf("out of scope: %s files are excluded", ext)
+			}
+		}
+	}
+	link := u.String()
+	for _, re := range s.Exclude {
+		if re.MatchString(link) {
+			return false, fmt.Sprintf("out of scope: matches exclude pattern %s", re)
+		}
+	}
+	if len(s.Include) > 0 {
+		for _, re := range s.Include {
+			if re.MatchString(link) {
+				return true, ""
+			}
+		}
+		return false, "out of scope: doesn't match any include pattern"
+	}
+	return true, ""
+}
+
+// underPath reports if urlPath is prefix or below it, whole segments are
+// compared since normalized urls lose their trailing slash.
+func underPath(urlPath, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" {
+		return true
+	}
+	return urlPath == prefix || strings.HasPrefix(urlPath, prefix+"/")
+}
+
+func registrableDomain(host string) string {
+	domain, err := publicsuffix.EffectiveTLDPlusOne(host)
+	if err != nil {
+		return host
+	}
+	return domain
+}
@@ -0,0 +1,45 @@
+package crawl
+
+import (
+	"net/url"
+	"regexp"
+	"testing"
+)
+
+func TestScopeRules(t *testing.T) {
+	seed, _ := url.Parse("https://docs.example.co.uk/guide/")
+	testCases := []struct {
+		name    string
+		rules   ScopeRules
+		link    string
+		allowed bool
+	}{
+		{name: "All allows other sites", rules: ScopeRules{Mode: ScopeAll}, link: "https://other.com/", allowed: true},
+		{name: "Host allows same host", rules: ScopeRules{Mode: ScopeHost}, link: "https://DOCS.example.co.uk/x", allowed: true},
+		{name: "Host blocks sibling subdomain", rules: ScopeRules{Mode: ScopeHost}, link: "https://www.example.co.uk/", allowed: false},
+		{name: "Subdomains allows child", rules: ScopeRules{Mode: ScopeSubdomains}, link: "https://api.docs.example.co.uk/", allowed: true},
+		{name: "Subdomains blocks parent", rules: ScopeRules{Mode: ScopeSubdomains}, link: "https://example.co.uk/", allowed: false},
+		{name: "Domain allows sibling", rules: ScopeRules{Mode: ScopeDomain}, link: "https://www.example.co.uk/", allowed: true},
+		{name: "Domain blocks other registrable domain", rules: ScopeRules{Mode: ScopeDomain}, link: "https://other.co.uk/", allowed: false},
+		{name: "Path prefix", rules: ScopeRules{PathPrefix: "/guide/"}, link: "https://docs.example.co.uk/blog/", allowed: false},
+		{name: "Path prefix itself", rules: ScopeRules{PathPrefix: "/guide/"}, link: "https://docs.example.co.uk/guide", allowed: true},
+		{name: "Path prefix below", rules: ScopeRules{PathPrefix: "/guide"}, link: "https://docs.example.co.uk/guide/intro", allowed: true},
+		{name: "Path prefix on a segment", rules: ScopeRules{PathPrefix: "/guide"}, link: "https://docs.example.co.uk/guides", allowed: false},
+		{name: "Excluded extension", rules: ScopeRules{ExcludeExtensions: []string{"pdf", ".ZIP"}}, link: "https://docs.example.co.uk/a.zip", allowed: false},
+		{name: "Exclude regex", rules: ScopeRules{Exclude: []*regexp.Regexp{regexp.MustCompile(`/login`)}}, link: "https://docs.example.co.uk/login", allowed: false},
+		{name: "Include regex match", rules: ScopeRules{Include: []*regexp.Regexp{regexp.MustCompile(`/guide/`)}}, link: "https://docs.example.co.uk/guide/a", allowed: true},
+		{name: "Include regex miss", rules: ScopeRules{Include: []*regexp.Regexp{regexp.MustCompile(`/guide/`)}}, link: "https://docs.example.co.uk/blog", allowed: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			u, err := url.Parse(tc.link)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed, reason := tc.rules.Allows(seed, u)
+			if allowed != tc.allowed {
+				t.Errorf("Expected allowed=%v for %s, got %v (%s)", tc.allowed, tc.link, allowed, reason)
+			}
+		})
+	}
+}
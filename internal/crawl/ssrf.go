@@ -0,0 +1,110 @@
+package crawl
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"syscall"
+)
+
+var reservedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("192.0.2.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("198.51.100.0/24"),
+	netip.MustParsePrefix("203.0.113.0/24"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("100::/64"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+type BlockedAddressError struct {
+	Addr netip.Addr
+}
+
+func (e *BlockedAddressError) Error() string {
+	return fmt.Sprintf("connection to %s blocked: private or reserved address", e.Addr)
+}
+
+// IPGuard refuses connections to private, loopback, link-local and other
+// reserved addresses unless they are inside one of the allowed networks.
+// It runs on every dial, after dns resolution, so redirects and dns
+// rebinding can't reach an internal host.
+type IPGuard struct {
+	Allowed []netip.Prefix
+}
+
+func NewIPGuard(allowed []netip.Prefix) *IPGuard {
+	return &IPGuard{Allowed: allowed}
+}
+
+func ParseNetworks(networks string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, network := range strings.Split(networks, ",") {
+		network = strings.TrimSpace(network)
+		if network == "" {
+			continue
+		}
+		if !strings.Contains(network, "/") {
+			addr, err := netip.ParseAddr(network)
+			if err != nil {
+				return nil, err
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(network)
+		if err != nil {
+			return nil, err
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+func isReservedAddr(addr netip.Addr) bool {
+	if addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() || addr.IsMulticast() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() {
+		return true
+	}
+	if addr == netip.AddrFrom4([4]byte{255, 255, 255, 255}) {
+		return true
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *IPGuard) Check(addr netip.Addr) error {
+	addr = addr.Unmap()
+	for _, network := range g.Allowed {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+	if isReservedAddr(addr) {
+		return &BlockedAddressError{Addr: addr}
+	}
+	return nil
+}
+
+// Control is meant for net.Dialer.Control, address is always the resolved ip
+// the dialer is about to connect to.
+func (g *IPGuard) Control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return fmt.Errorf("connection to %s blocked: not an ip address", host)
+	}
+	return g.Check(addr)
+}
@@ -0,0 +1,68 @@
+package crawl
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
+)
+
+func TestIPGuardCheck(t *testing.T) {
+	guard := NewIPGuard(nil)
+	blocked := []string{"127.0.0.1", "10.0.0.5", "172.16.3.4", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fe80::1", "fd00::1", "::ffff:10.0.0.1"}
+	for _, ip := range blocked {
+		if err := guard.Check(netip.MustParseAddr(ip)); err == nil {
+			t.Errorf("Expected %s to be blocked", ip)
+		}
+	}
+	allowed := []string{"8.8.8.8", "1.1.1.1", "2606:4700:4700::1111"}
+	for _, ip := range allowed {
+		if err := guard.Check(netip.MustParseAddr(ip)); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", ip, err)
+		}
+	}
+	networks, err := ParseNetworks("10.0.0.0/8, 127.0.0.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	guard = NewIPGuard(networks)
+	if err := guard.Check(netip.MustParseAddr("10.1.2.3")); err != nil {
+		t.Errorf("Expected an allowlisted network to be allowed, got %v", err)
+	}
+	if err := guard.Check(netip.MustParseAddr("127.0.0.2")); err == nil {
+		t.Error("Expected only the allowlisted ip to be allowed")
+	}
+}
+
+func TestIPGuardOnTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`<a href="/a">A</a>`))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher(time.Second, NewTransport(NewIPGuard(nil)))
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	_, err := fetcher.Fetch(req)
+	var blocked *BlockedAddressError
+	if !errors.As(err, &blocked) {
+		t.Fatalf("Expected the loopback server to be blocked, got %v", err)
+	}
+
+	crawler := New(server.URL, WithSchemePolicy(validate.AllowHTTP), WithHostLimits(2, 0))
+	err = crawler.Crawl(context.Background())
+	var skipErr *SkipError
+	if !errors.As(err, &skipErr) {
+		t.Fatalf("Expected the crawl of an internal host to be skipped, got %v", err)
+	}
+
+	loopback, _ := ParseNetworks("127.0.0.0/8,::1")
+	crawler = New(server.URL, WithSchemePolicy(validate.AllowHTTP), WithHostLimits(2, 0), WithAllowedNetworks(loopback))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatalf("Expected an allowlisted host to be crawled, got %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package crawl
+
+import "strings"
+
+// maxLinkDensity is the share of a block's words that can be link text before
+// the block is treated as a menu or a list of related links.
+const maxLinkDensity = 0.5
+
+// boilerplateTags never hold the main content of a page. Forms are left out
+// since some frameworks wrap the whole page in one, search forms are caught by
+// their role or name instead.
+var boilerplateTags = map[string]bool{
+	"nav": true, "footer": true, "header": true, "aside": true, "button": true,
+	"select": true, "svg": true, "menu": true, "dialog": true, "iframe": true,
+}
+
+// boilerplateRoles are the aria landmarks around the main content.
+var boilerplateRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "search": true, "dialog": true,
+}
+
+// boilerplateNames are class names or ids that mark menus, sidebars and
+// other page furniture. They match whole, so modifiers like "no-sidebar"
+// are not mistaken for the furniture they mention.
+var boilerplateNames = map[string]bool{
+	"nav": true, "navbar": true, "navigation": true, "menu": true, "footer": true, "sidebar": true,
+	"breadcrumb": true, "comment": true, "cookie": true, "share": true, "social": true, "ads": true,
+	"advert": true, "promo": true, "related": true, "popup": true, "modal": true, "banner": true,
+	"newsletter": true, "pagination": true, "search": true, "search-form": true, "site-nav": true, "site-footer": true, "cookie-banner": true,
+	"cookie-notice": true, "related-posts": true, "share-buttons": true, "social-links": true,
+}
+
+// contentTags wrap the whole page or its main content, their class and id
+// describe the page instead of a part of it.
+var contentTags = map[string]bool{"html": true, "body": true, "main": true, "article": true}
+
+// blockTags split the text of a page in blocks that are kept or dropped as a
+// whole.
+var blockTags = map[string]bool{
+	"p": true, "div": true, "section": true, "article": true, "main": true, "li": true, "ul": true, "ol": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "td": true, "th": true, "tr": true,
+	"table": true, "blockquote": true, "pre": true, "br": true, "dd": true, "dt": true, "figcaption": true, "body": true,
+}
+
+// voidTags have no end tag, so they never open an element.
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true, "input": true,
+	"link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+type textBlock struct {
+	text      string
+	inContent bool
+}
+
+// openElement is an element the extractor waits to be closed, nested counts
+// the elements with the same tag open inside it.
+type openElement struct {
+	tag    string
+	nested int
+}
+
+// openNested counts tag when it is nested in the innermost element of stack.
+func openNested(stack []openElement, tag string) {
+	if n := len(stack); n > 0 && stack[n-1].tag == tag {
+		stack[n-1].nested++
+	}
+}
+
+// closeNested returns stack without its innermost element when tag closes it.
+func closeNested(stack []openElement, tag string) []openElement {
+	n := len(stack)
+	if n == 0 || stack[n-1].tag != tag {
+		return stack
+	}
+	if stack[n-1].nested > 0 {
+		stack[n-1].nested--
+		return stack
+	}
+	return stack[:n-1]
+}
+
+// textExtractor keeps the readable text of a page while its tokens are read:
+// boilerplate elements are skipped, blocks made mostly of links are dropped
+// and when the page marks its main content only that is kept.
+type textExtractor struct {
+	skipped    []openElement
+	content    []openElement
+	hasContent bool
+	inLink     bool
+	block      strings.Builder
+	words      int
+	linkWords  int
+	blocks     []textBlock
+}
+
+func (e *textExtractor) start(tag string, class, id, role string, selfClosing bool) {
+	if blockTags[tag] {
+		e.flush()
+	}
+	if selfClosing || voidTags[tag] {
+		return
+	}
+	if len(e.skipped) > 0 {
+		openNested(e.skipped, tag)
+		return
+	}
+	// an article keeps its own header, it usually has the byline
+	boilerplate := boilerplateTags[tag] && (tag != "header" || len(e.content) == 0)
+	named := !contentTags[tag] && (boilerplateName(class) || boilerplateName(id))
+	if boilerplate || boilerplateRoles[strings.ToLower(role)] || named {
+		e.skipped = append(e.skipped, openElement{tag: tag})
+		return
+	}
+	if tag == "a" {
+		e.inLink = true
+	}
+	if tag == "main" || tag == "article" || strings.EqualFold(role, "main") {
+		e.flush()
+		e.content = append(e.content, openElement{tag: tag})
+		e.hasContent = true
+	} else {
+		openNested(e.content, tag)
+	}
+}
+
+func (e *textExtractor) end(tag string) {
+	if blockTags[tag] {
+		e.flush()
+	}
+	if len(e.skipped) > 0 {
+		e.skipped = closeNested(e.skipped, tag)
+		return
+	}
+	if tag == "a" {
+		e.inLink = false
+	}
+	if n := len(e.content); n > 0 && e.content[n-1].tag == tag && e.content[n-1].nested == 0 {
+		e.flush()
+	}
+	e.content = closeNested(e.content, tag)
+}
+
+func (e *textExtractor) text(text string) {
+	if len(e.skipped) > 0 {
+		return
+	}
+	words := len(strings.Fields(text))
+	e.words += words
+	if e.inLink {
+		e.linkWords += words
+	}
+	e.block.WriteString(text)
+}
+
+// flush ends the current block, keeping it unless most of it is link text.
+func (e *textExtractor) flush() {
+	text := collapseSpaces(e.block.String())
+	if text != "" && float64(e.linkWords) <= maxLinkDensity*float64(e.words) {
+		e.blocks = append(e.blocks, textBlock{text: text, inContent: len(e.content) > 0})
+	}
+	e.block.Reset()
+	e.words = 0
+	e.linkWords = 0
+}
+
+// result returns the kept blocks one per line.
+func (e *textExtractor) result() string {
+	e.flush()
+	var lines []string
+	for _, block := range e.blocks {
+		if block.inContent || !e.hasContent {
+			lines = append(lines, block.text)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// boilerplateName reports if one of the space separated names of a class or
+// id is like "nav" or "comments".
+func boilerplateName(value string) bool {
+	for _, field := range strings.Fields(strings.ToLower(value)) {
+		if boilerplateNames[field] || boilerplateNames[strings.TrimSuffix(field, "s")] {
+			return true
+		}
+	}
+	return false
+}
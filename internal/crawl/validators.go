@@ -0,0 +1,30 @@
+package crawl
+
+import "net/http"
+
+// CacheValidators are the ETag and Last-Modified a page was served with,
+// sent back on a recrawl so an unchanged page answers 304 without a body.
+type CacheValidators struct {
+	ETag         string
+	LastModified string
+}
+
+// ValidatorLookup returns the validators saved for a url by a previous crawl.
+type ValidatorLookup func(url string) (CacheValidators, bool)
+
+// LinkLookup returns the links a previous crawl found on a url and the robots
+// directives the page had then, a page that answered 304 is expanded from them.
+type LinkLookup func(url string) ([]Link, RobotsDirectives, bool)
+
+func (v CacheValidators) apply(req *http.Request) {
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+func validatorsOf(resp *http.Response) CacheValidators {
+	return CacheValidators{ETag: resp.Header.Get("ETag"), LastModified: resp.Header.Get("Last-Modified")}
+}
@@ -0,0 +1,105 @@
+package crawl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestCrawlConditionalRequests(t *testing.T) {
+	cached := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+		status := http.StatusOK
+		if req.Header.Get("If-None-Match") == `"v1"` || req.Header.Get("If-Modified-Since") == "Mon, 02 Jan 2006 15:04:05 GMT" {
+			status = http.StatusNotModified
+		}
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Etag": {`"v1"`}, "Last-Modified": {"Mon, 02 Jan 2006 15:04:05 GMT"}},
+			Body:       io.NopCloser(strings.NewReader(`<a href="/a">A</a>`)),
+			Request:    req,
+		}, nil
+	})
+	crawler := New("https://example.com/", WithFetcher(cached), WithHostLimits(2, 0))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	page := crawler.Pages[0]
+	if page.NotModified || page.Validators.ETag != `"v1"` || page.Validators.LastModified == "" || len(page.InfoCrawled) != 1 {
+		t.Fatalf("Expected the validators of a full download to be recorded, got %+v", page)
+	}
+
+	lookup := func(link string) (CacheValidators, bool) {
+		if link == "https://example.com/" {
+			return CacheValidators{ETag: `"v1"`}, true
+		}
+		return CacheValidators{}, false
+	}
+	crawler = New("https://example.com/", WithFetcher(cached), WithHostLimits(2, 0), WithValidators(lookup))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	page = crawler.Pages[0]
+	if !page.NotModified || page.Status != http.StatusNotModified || len(page.InfoCrawled) != 0 || page.Category != CategorySuccess {
+		t.Fatalf("Expected an unchanged page to be reported as not modified, got %+v", page)
+	}
+
+	stored := func(link string) ([]Link, RobotsDirectives, bool) {
+		if link == "https://example.com/" {
+			return []Link{{Source: link, Target: "https://example.com/a", Text: "A"}}, RobotsDirectives{}, true
+		}
+		return nil, RobotsDirectives{}, false
+	}
+	crawler = New("https://example.com/", WithMaxDepth(2), WithFetcher(cached), WithHostLimits(2, 0), WithValidators(lookup), WithStoredLinks(stored))
+	if err := crawler.Crawl(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := crawler.CrawlChildrenWithDepth(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(crawler.Pages) != 2 || crawler.Pages[1].URL != "https://example.com/a" || crawler.Pages[1].Depth != 1 {
+		t.Fatalf("Expected an unchanged page to be expanded from its stored links, got %+v", crawler.Pages)
+	}
+}
+
+func TestCrawlNotModifiedNofollow(t *testing.T) {
+	testCases := []struct {
+		name   string
+		header string
+		stored RobotsDirectives
+	}{
+		{name: "X-Robots-Tag on the 304", header: "nofollow"},
+		{name: "Stored meta nofollow", stored: RobotsDirectives{NoFollow: true}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fetched := make(map[string]int)
+			fetcher := FetcherFunc(func(req *http.Request) (*http.Response, error) {
+				fetched[req.URL.Path]++
+				resp := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("")), Request: req}
+				if req.URL.Path == "/" {
+					resp.StatusCode = http.StatusNotModified
+					if tc.header != "" {
+						resp.Header.Set("X-Robots-Tag", tc.header)
+					}
+				}
+				return resp, nil
+			})
+			lookup := func(link string) (CacheValidators, bool) {
+				return CacheValidators{ETag: `"v1"`}, link == "https://example.com/"
+			}
+			stored := func(link string) ([]Link, RobotsDirectives, bool) {
+				return []Link{{Source: link, Target: "https://example.com/secret", Text: "Secret"}}, tc.stored, link == "https://example.com/"
+			}
+			crawler := New("https://example.com/", WithMaxDepth(2), WithFetcher(fetcher), WithHostLimits(2, 0), WithValidators(lookup), WithStoredLinks(stored))
+			if err := crawler.Crawl(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			crawler.CrawlChildrenWithDepth(context.Background())
+			if fetched["/secret"] != 0 {
+				t.Errorf("Expected the stored links of a nofollow page not to be followed, got %v", fetched)
+			}
+		})
+	}
+}
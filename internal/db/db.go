@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/AgustinPagotto/go-webcrawler/internal/crawl"
@@ -44,8 +45,12 @@ func (s *Store) InitiateDB() error {
 	CREATE TABLE IF NOT EXISTS child_webs(
 		id INTEGER NOT NULL PRIMARY KEY,
 		web_crawled_id INTEGER NOT NULL,
+		source_url TEXT,
 		url_text TEXT,
 		url TEXT,
+		rel TEXT,
+		depth INTEGER,
+		position INTEGER,
 		FOREIGN KEY (web_crawled_id) REFERENCES webs_crawled(id) ON DELETE CASCADE
 	);
 	`
@@ -53,11 +58,170 @@ func (s *Store) InitiateDB() error {
 	if err != nil {
 		return fmt.Errorf("Error trying to create child_webs table: \n%v", err)
 	}
+	childColumns := [][2]string{
+		{"source_url", "TEXT"},
+		{"rel", "TEXT"},
+		{"depth", "INTEGER"},
+		{"position", "INTEGER"},
+	}
+	for _, column := range childColumns {
+		err = s.addColumnIfMissing("child_webs", column[0], column[1])
+		if err != nil {
+			return err
+		}
+	}
+	sqlQuery = `
+	CREATE TABLE IF NOT EXISTS redirects(
+		id INTEGER NOT NULL PRIMARY KEY,
+		web_crawled_id INTEGER NOT NULL,
+		start_url TEXT,
+		depth INTEGER,
+		hop INTEGER,
+		url TEXT,
+		status INTEGER,
+		error TEXT,
+		FOREIGN KEY (web_crawled_id) REFERENCES webs_crawled(id) ON DELETE CASCADE
+	);
+	`
+	_, err = s.db.Exec(sqlQuery)
+	if err != nil {
+		return fmt.Errorf("Error trying to create redirects table: \n%v", err)
+	}
+	sqlQuery = `
+	CREATE TABLE IF NOT EXISTS fetch_errors(
+		id INTEGER NOT NULL PRIMARY KEY,
+		web_crawled_id INTEGER,
+		url TEXT,
+		depth INTEGER,
+		category TEXT,
+		status INTEGER,
+		error TEXT,
+		fetched_at DATETIME,
+		FOREIGN KEY (web_crawled_id) REFERENCES webs_crawled(id) ON DELETE CASCADE
+	);
+	`
+	_, err = s.db.Exec(sqlQuery)
+	if err != nil {
+		return fmt.Errorf("Error trying to create fetch_errors table: \n%v", err)
+	}
+	sqlQuery = `
+	CREATE TABLE IF NOT EXISTS page_cache(
+		url TEXT NOT NULL PRIMARY KEY,
+		etag TEXT,
+		last_modified TEXT,
+		last_crawled DATETIME,
+		nofollow INTEGER
+	);
+	`
+	_, err = s.db.Exec(sqlQuery)
+	if err != nil {
+		return fmt.Errorf("Error trying to create page_cache table: \n%v", err)
+	}
+	err = s.addColumnIfMissing("page_cache", "nofollow", "INTEGER")
+	if err != nil {
+		return err
+	}
+	sqlQuery = `
+	CREATE TABLE IF NOT EXISTS assets(
+		id INTEGER NOT NULL PRIMARY KEY,
+		web_crawled_id INTEGER NOT NULL,
+		page_url TEXT,
+		url TEXT,
+		kind TEXT,
+		content_type TEXT,
+		size INTEGER,
+		status INTEGER,
+		unchecked TEXT,
+		FOREIGN KEY (web_crawled_id) REFERENCES webs_crawled(id) ON DELETE CASCADE
+	);
+	`
+	_, err = s.db.Exec(sqlQuery)
+	if err != nil {
+		return fmt.Errorf("Error trying to create assets table: \n%v", err)
+	}
+	err = s.addColumnIfMissing("assets", "unchecked", "TEXT")
+	if err != nil {
+		return err
+	}
+	sqlQuery = `
+	CREATE TABLE IF NOT EXISTS pages(
+		id INTEGER NOT NULL PRIMARY KEY,
+		web_crawled_id INTEGER NOT NULL,
+		url TEXT NOT NULL,
+		depth INTEGER,
+		title TEXT,
+		description TEXT,
+		keywords TEXT,
+		robots TEXT,
+		lang TEXT,
+		canonical TEXT,
+		word_count INTEGER,
+		body_text TEXT,
+		UNIQUE (web_crawled_id, url),
+		FOREIGN KEY (web_crawled_id) REFERENCES webs_crawled(id) ON DELETE CASCADE
+	);
+	`
+	_, err = s.db.Exec(sqlQuery)
+	if err != nil {
+		return fmt.Errorf("Error trying to create pages table: \n%v", err)
+	}
+	err = s.addColumnIfMissing("pages", "body_text", "TEXT")
+	if err != nil {
+		return err
+	}
+	sqlQuery = `
+	CREATE TABLE IF NOT EXISTS headings(
+		id INTEGER NOT NULL PRIMARY KEY,
+		page_id INTEGER NOT NULL,
+		level INTEGER,
+		text TEXT,
+		position INTEGER,
+		FOREIGN KEY (page_id) REFERENCES pages(id) ON DELETE CASCADE
+	);
+	`
+	_, err = s.db.Exec(sqlQuery)
+	if err != nil {
+		return fmt.Errorf("Error trying to create headings table: \n%v", err)
+	}
 	sqlQuery = `CREATE INDEX IF NOT EXISTS idx_child_webs_url_and_text ON child_webs(url_text, url);`
 	_, err = s.db.Exec(sqlQuery)
 	if err != nil {
 		return fmt.Errorf("Error trying to create index of url from child_webs table: \n%v", err)
 	}
+	sqlQuery = `CREATE INDEX IF NOT EXISTS idx_child_webs_source_url ON child_webs(source_url);`
+	_, err = s.db.Exec(sqlQuery)
+	if err != nil {
+		return fmt.Errorf("Error trying to create index of source url from child_webs table: \n%v", err)
+	}
+	return nil
+}
+
+// addColumnIfMissing upgrades databases created before a column existed,
+// CREATE TABLE IF NOT EXISTS leaves their old schema untouched.
+func (s *Store) addColumnIfMissing(table, column, columnType string) error {
+	rows, err := s.db.Query(fmt.Sprintf("PRAGMA table_info(%s);", table))
+	if err != nil {
+		return fmt.Errorf("Error trying to read the columns of %s table: \n%v", table, err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, primaryKey int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &primaryKey); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	_, err = s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", table, column, columnType))
+	if err != nil {
+		return fmt.Errorf("Error trying to add column %s to %s table: \n%v", column, table, err)
+	}
 	return nil
 }
 
@@ -79,9 +243,13 @@ func (s *Store) EnterNewChilds(crawler crawl.Crawler) error {
 	} else if err != nil {
 		return fmt.Errorf("db query failed: %w", err)
 	}
-	sqlQuery = "INSERT INTO child_webs (web_crawled_id, url_text, url) VALUES (?,?,?);"
-	for url_text, url := range crawler.TextLinksCrawled {
-		_, err = s.db.Exec(sqlQuery, id, url_text, url)
+	sqlQuery = "INSERT INTO child_webs (web_crawled_id, source_url, url_text, url, rel, depth, position) VALUES (?,?,?,?,?,?,?);"
+	for _, link := range crawler.TextLinksCrawled {
+		source := link.Source
+		if source == "" {
+			source = crawler.URL
+		}
+		_, err = s.db.Exec(sqlQuery, id, source, link.Text, link.Target, strings.Join(link.Rel, " "), link.Depth, link.Position)
 		if err != nil {
 			return fmt.Errorf("couldn't insert the url: \n%v", err)
 		}
@@ -89,30 +257,528 @@ func (s *Store) EnterNewChilds(crawler crawl.Crawler) error {
 	return nil
 }
 
+// SaveStream stores the pages of a crawl as they arrive, the seed page comes
+// first and every link found is attached to it. Pages marked noindex are not
+// stored. The outcome of every url that failed is kept in fetch_errors, urls
+// the crawl was interrupted before fetching keep what was saved before.
+// After an error it keeps draining pages so the crawl is never blocked, and
+// returns the first error.
+func (s *Store) SaveStream(pages <-chan crawl.Result) (int, error) {
+	var seedId int64
+	var firstErr error
+	saved := 0
+	for page := range pages {
+		if firstErr != nil || errors.Is(page.Error, crawl.ErrCanceled) {
+			continue
+		}
+		if page.Error == nil && page.Depth == 0 {
+			seedId, firstErr = s.upsertSeed(page)
+			if firstErr != nil {
+				continue
+			}
+		}
+		firstErr = s.saveFetchError(seedId, page)
+		if firstErr != nil || seedId == 0 {
+			continue
+		}
+		if page.Error != nil {
+			firstErr = s.insertRedirects(seedId, page)
+			continue
+		}
+		firstErr = s.insertRedirects(seedId, page)
+		if firstErr == nil && page.Robots.NoIndex {
+			firstErr = s.removePage(seedId, page.URL)
+			continue
+		}
+		if firstErr == nil {
+			firstErr = s.insertLinks(seedId, page)
+		}
+		if firstErr == nil {
+			firstErr = s.savePageCache(page)
+		}
+		if firstErr == nil && page.Asset != nil {
+			firstErr = s.insertAsset(seedId, "", *page.Asset)
+		}
+		if firstErr == nil && page.Asset == nil && !page.NotModified {
+			firstErr = s.savePage(seedId, page)
+		}
+		if firstErr == nil {
+			saved++
+		}
+	}
+	return saved, firstErr
+}
+
+// saveFetchError replaces the outcome saved for the url of page, a seed that
+// failed before it was saved is kept without a webs_crawled row.
+func (s *Store) saveFetchError(seedId int64, page crawl.Result) error {
+	var webCrawledId sql.NullInt64
+	if seedId != 0 {
+		webCrawledId = sql.NullInt64{Int64: seedId, Valid: true}
+	}
+	_, err := s.db.Exec("DELETE FROM fetch_errors WHERE web_crawled_id IS ? AND url = ?;", webCrawledId, page.URL)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the old fetch error: \n%v", err)
+	}
+	category := page.Category
+	if category == "" {
+		category = crawl.Categorize(page)
+	}
+	if category == crawl.CategorySuccess {
+		return nil
+	}
+	var errText sql.NullString
+	if page.Error != nil {
+		errText = sql.NullString{String: page.Error.Error(), Valid: true}
+	}
+	sqlQuery := "INSERT INTO fetch_errors (web_crawled_id, url, depth, category, status, error, fetched_at) VALUES (?,?,?,?,?,?,?);"
+	_, err = s.db.Exec(sqlQuery, webCrawledId, page.URL, page.Depth, string(category), page.Status, errText, page.FetchedAt)
+	if err != nil {
+		return fmt.Errorf("couldn't insert the fetch error: \n%v", err)
+	}
+	return nil
+}
+
+// FetchErrors returns every url of the crawl of url that wasn't crawled
+// successfully, ordered by depth.
+func (s *Store) FetchErrors(url string) ([]crawl.FetchError, error) {
+	sqlQuery := `
+	SELECT f.url, f.depth, f.category, f.status, COALESCE(f.error, '') FROM fetch_errors f
+	LEFT JOIN webs_crawled w ON w.id = f.web_crawled_id
+	WHERE w.url = ? OR (f.web_crawled_id IS NULL AND f.url = ?)
+	ORDER BY f.depth, f.id;
+	`
+	rows, err := s.db.Query(sqlQuery, url, url)
+	if err != nil {
+		return nil, fmt.Errorf("consult of fetch errors in db query failed: %w", err)
+	}
+	defer rows.Close()
+	var fetchErrors []crawl.FetchError
+	for rows.Next() {
+		var fetchErr crawl.FetchError
+		var category, errText string
+		if err := rows.Scan(&fetchErr.URL, &fetchErr.Depth, &category, &fetchErr.Status, &errText); err != nil {
+			return nil, err
+		}
+		fetchErr.Category = crawl.ErrorCategory(category)
+		if errText != "" {
+			fetchErr.Err = errors.New(errText)
+		}
+		fetchErrors = append(fetchErrors, fetchErr)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return fetchErrors, nil
+}
+
+func (s *Store) upsertSeed(page crawl.Result) (int64, error) {
+	var id int64
+	sqlQuery := "SELECT id FROM webs_crawled WHERE url = ?;"
+	err := s.db.QueryRow(sqlQuery, page.URL).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		sqlQuery = "INSERT INTO webs_crawled (url, status, last_crawled) VALUES (?,?,?);"
+		res, err := s.db.Exec(sqlQuery, page.URL, page.Status, page.FetchedAt)
+		if err != nil {
+			return 0, fmt.Errorf("Error trying to insert new url: \n%v", err)
+		}
+		return res.LastInsertId()
+	} else if err != nil {
+		return 0, fmt.Errorf("consult of url in db query failed: %w", err)
+	}
+	if page.NotModified {
+		sqlQuery = "UPDATE webs_crawled SET last_crawled = ? WHERE id = ?;"
+		_, err = s.db.Exec(sqlQuery, page.FetchedAt, id)
+	} else {
+		sqlQuery = "UPDATE webs_crawled SET status = ?, last_crawled = ? WHERE id = ?;"
+		_, err = s.db.Exec(sqlQuery, page.Status, page.FetchedAt, id)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("Error trying to update the crawled url: \n%v", err)
+	}
+	return id, nil
+}
+
+// savePageCache keeps the validators and the nofollow state of a downloaded
+// page for the next recrawl, a page that answered 304 only gets its last crawl
+// time updated.
+func (s *Store) savePageCache(page crawl.Result) error {
+	if page.NotModified {
+		_, err := s.db.Exec("UPDATE page_cache SET last_crawled = ? WHERE url = ?;", page.FetchedAt, page.URL)
+		if err != nil {
+			return fmt.Errorf("Error trying to update the page cache: \n%v", err)
+		}
+		return nil
+	}
+	sqlQuery := `
+	INSERT INTO page_cache (url, etag, last_modified, last_crawled, nofollow) VALUES (?,?,?,?,?)
+	ON CONFLICT(url) DO UPDATE SET etag = excluded.etag, last_modified = excluded.last_modified, last_crawled = excluded.last_crawled,
+		nofollow = excluded.nofollow;
+	`
+	_, err := s.db.Exec(sqlQuery, page.URL, page.Validators.ETag, page.Validators.LastModified, page.FetchedAt, page.Robots.NoFollow)
+	if err != nil {
+		return fmt.Errorf("Error trying to save the page cache: \n%v", err)
+	}
+	return nil
+}
+
+// savePage keeps the record of an html page, replacing the one saved by a
+// previous crawl.
+func (s *Store) savePage(seedId int64, page crawl.Result) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	info := page.Page
+	sqlQuery := `
+	INSERT INTO pages (web_crawled_id, url, depth, title, description, keywords, robots, lang, canonical, word_count, body_text) VALUES (?,?,?,?,?,?,?,?,?,?,?)
+	ON CONFLICT(web_crawled_id, url) DO UPDATE SET depth = excluded.depth, title = excluded.title, description = excluded.description,
+		keywords = excluded.keywords, robots = excluded.robots, lang = excluded.lang, canonical = excluded.canonical, word_count = excluded.word_count,
+		body_text = excluded.body_text;
+	`
+	_, err = tx.Exec(sqlQuery, seedId, page.URL, page.Depth, info.Title, info.Description, info.Keywords, info.Robots, info.Lang, info.Canonical, info.WordCount, info.Text)
+	if err != nil {
+		return fmt.Errorf("couldn't save the page: \n%v", err)
+	}
+	var pageId int64
+	err = tx.QueryRow("SELECT id FROM pages WHERE web_crawled_id = ? AND url = ?;", seedId, page.URL).Scan(&pageId)
+	if err != nil {
+		return fmt.Errorf("consult of the saved page failed: %w", err)
+	}
+	_, err = tx.Exec("DELETE FROM headings WHERE page_id = ?;", pageId)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the old headings: \n%v", err)
+	}
+	for position, heading := range info.Headings {
+		_, err = tx.Exec("INSERT INTO headings (page_id, level, text, position) VALUES (?,?,?,?);", pageId, heading.Level, heading.Text, position)
+		if err != nil {
+			return fmt.Errorf("couldn't insert the heading: \n%v", err)
+		}
+	}
+	_, err = tx.Exec("DELETE FROM assets WHERE web_crawled_id = ? AND page_url = ?;", seedId, page.URL)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the old assets of the page: \n%v", err)
+	}
+	sqlQuery = "INSERT INTO assets (web_crawled_id, page_url, url, kind, content_type, size, status, unchecked) VALUES (?,?,?,?,?,?,?,?);"
+	for _, asset := range info.Assets {
+		_, err = tx.Exec(sqlQuery, seedId, page.URL, asset.URL, asset.Kind, asset.ContentType, asset.Size, asset.Status, asset.Unchecked)
+		if err != nil {
+			return fmt.Errorf("couldn't insert the asset: \n%v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// removePage deletes the record, links and assets of a page that asked not to be
+// indexed, they may have been saved by a crawl before it did.
+func (s *Store) removePage(seedId int64, pageUrl string) error {
+	_, err := s.db.Exec("DELETE FROM pages WHERE web_crawled_id = ? AND url = ?;", seedId, pageUrl)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the noindex page: \n%v", err)
+	}
+	_, err = s.db.Exec("DELETE FROM child_webs WHERE web_crawled_id = ? AND source_url = ?;", seedId, pageUrl)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the links of the noindex page: \n%v", err)
+	}
+	_, err = s.db.Exec("DELETE FROM assets WHERE web_crawled_id = ? AND page_url = ?;", seedId, pageUrl)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the assets of the noindex page: \n%v", err)
+	}
+	return nil
+}
+
+// pageInfo returns the record saved for pageUrl on the crawl seedId, an
+// empty one when the page was never saved.
+func (s *Store) pageInfo(seedId int, pageUrl string) (crawl.PageInfo, error) {
+	var info crawl.PageInfo
+	var pageId int
+	sqlQuery := `
+	SELECT id, COALESCE(title, ''), COALESCE(description, ''), COALESCE(keywords, ''), COALESCE(robots, ''),
+		COALESCE(lang, ''), COALESCE(canonical, ''), COALESCE(word_count, 0), COALESCE(body_text, '')
+	FROM pages WHERE web_crawled_id = ? AND url = ?;
+	`
+	err := s.db.QueryRow(sqlQuery, seedId, pageUrl).Scan(&pageId, &info.Title, &info.Description, &info.Keywords, &info.Robots,
+		&info.Lang, &info.Canonical, &info.WordCount, &info.Text)
+	if errors.Is(err, sql.ErrNoRows) {
+		return info, nil
+	} else if err != nil {
+		return info, fmt.Errorf("consult of the page in db query failed: %w", err)
+	}
+	rows, err := s.db.Query("SELECT level, text FROM headings WHERE page_id = ? ORDER BY position;", pageId)
+	if err != nil {
+		return info, fmt.Errorf("consult of the headings in db query failed: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var heading crawl.Heading
+		if err := rows.Scan(&heading.Level, &heading.Text); err != nil {
+			return info, err
+		}
+		info.Headings = append(info.Headings, heading)
+	}
+	return info, rows.Err()
+}
+
+// insertAsset replaces the asset saved for the same url on the same page, a
+// pageUrl of "" is an asset that was crawled as a link.
+func (s *Store) insertAsset(seedId int64, pageUrl string, asset crawl.Asset) error {
+	_, err := s.db.Exec("DELETE FROM assets WHERE web_crawled_id = ? AND page_url = ? AND url = ?;", seedId, pageUrl, asset.URL)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the old asset: \n%v", err)
+	}
+	sqlQuery := "INSERT INTO assets (web_crawled_id, page_url, url, kind, content_type, size, status, unchecked) VALUES (?,?,?,?,?,?,?,?);"
+	_, err = s.db.Exec(sqlQuery, seedId, pageUrl, asset.URL, asset.Kind, asset.ContentType, asset.Size, asset.Status, asset.Unchecked)
+	if err != nil {
+		return fmt.Errorf("couldn't insert the asset: \n%v", err)
+	}
+	return nil
+}
+
+// Assets returns every resource that wasn't an html page found on the crawl
+// of url, the ones loaded by a page have it as their Source. They are ordered
+// by kind.
+func (s *Store) Assets(url string) ([]crawl.Asset, error) {
+	sqlQuery := `
+	SELECT COALESCE(a.page_url, ''), a.url, a.kind, COALESCE(a.content_type, ''), COALESCE(a.size, 0), COALESCE(a.status, 0),
+		COALESCE(a.unchecked, '') FROM assets a
+	JOIN webs_crawled w ON w.id = a.web_crawled_id
+	WHERE w.url = ? ORDER BY a.kind, a.id;
+	`
+	rows, err := s.db.Query(sqlQuery, url)
+	if err != nil {
+		return nil, fmt.Errorf("consult of assets in db query failed: %w", err)
+	}
+	defer rows.Close()
+	var assets []crawl.Asset
+	for rows.Next() {
+		var asset crawl.Asset
+		if err := rows.Scan(&asset.Source, &asset.URL, &asset.Kind, &asset.ContentType, &asset.Size, &asset.Status, &asset.Unchecked); err != nil {
+			return nil, err
+		}
+		assets = append(assets, asset)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// Validators returns the ETag and Last-Modified saved for url, it matches
+// crawl.ValidatorLookup so a recrawl can only download the pages that changed.
+func (s *Store) Validators(url string) (crawl.CacheValidators, bool) {
+	var validators crawl.CacheValidators
+	sqlQuery := "SELECT COALESCE(etag, ''), COALESCE(last_modified, '') FROM page_cache WHERE url = ?;"
+	err := s.db.QueryRow(sqlQuery, url).Scan(&validators.ETag, &validators.LastModified)
+	if err != nil || validators == (crawl.CacheValidators{}) {
+		return crawl.CacheValidators{}, false
+	}
+	return validators, true
+}
+
+// Links returns the links saved for the page at url and if it was marked
+// nofollow, it matches crawl.LinkLookup so a recrawl keeps following the
+// links of unchanged pages.
+func (s *Store) Links(url string) ([]crawl.Link, crawl.RobotsDirectives, bool) {
+	var directives crawl.RobotsDirectives
+	err := s.db.QueryRow("SELECT COALESCE(nofollow, 0) FROM page_cache WHERE url = ?;", url).Scan(&directives.NoFollow)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, directives, false
+	}
+	sqlQuery := "SELECT DISTINCT url_text, url, COALESCE(rel, ''), COALESCE(position, 0) FROM child_webs WHERE source_url = ? ORDER BY position;"
+	rows, err := s.db.Query(sqlQuery, url)
+	if err != nil {
+		return nil, directives, false
+	}
+	defer rows.Close()
+	var links []crawl.Link
+	for rows.Next() {
+		link := crawl.Link{Source: url}
+		var rel string
+		if err := rows.Scan(&link.Text, &link.Target, &rel, &link.Position); err != nil {
+			return nil, directives, false
+		}
+		link.Rel = strings.Fields(rel)
+		links = append(links, link)
+	}
+	if rows.Err() != nil || len(links) == 0 {
+		return nil, directives, false
+	}
+	return links, directives, true
+}
+
+// insertLinks skips links already saved by a previous crawl of the seed.
+func (s *Store) insertLinks(seedId int64, page crawl.Result) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	sqlQuery := `
+	INSERT INTO child_webs (web_crawled_id, source_url, url_text, url, rel, depth, position)
+	SELECT ?,?,?,?,?,?,? WHERE NOT EXISTS (
+		SELECT 1 FROM child_webs WHERE web_crawled_id = ? AND source_url = ? AND url_text = ? AND url = ? AND position = ?
+	);
+	`
+	for _, link := range page.InfoCrawled {
+		source := link.Source
+		if source == "" {
+			source = page.URL
+		}
+		_, err = tx.Exec(sqlQuery, seedId, source, link.Text, link.Target, strings.Join(link.Rel, " "), link.Depth, link.Position,
+			seedId, source, link.Text, link.Target, link.Position)
+		if err != nil {
+			return fmt.Errorf("couldn't insert the url: \n%v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// insertRedirects replaces the chain saved for the url the page was requested
+// with. Every hop gets a row, a chain that reached a page ends with a row for
+// it and a broken chain keeps the error on its last hop.
+func (s *Store) insertRedirects(seedId int64, page crawl.Result) error {
+	if len(page.Redirects) == 0 {
+		return nil
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	startUrl := page.Redirects[0].URL
+	_, err = tx.Exec("DELETE FROM redirects WHERE web_crawled_id = ? AND start_url = ?;", seedId, startUrl)
+	if err != nil {
+		return fmt.Errorf("couldn't delete the old redirect chain: \n%v", err)
+	}
+	sqlQuery := "INSERT INTO redirects (web_crawled_id, start_url, depth, hop, url, status, error) VALUES (?,?,?,?,?,?,?);"
+	for hop, redirect := range page.Redirects {
+		var hopErr sql.NullString
+		if page.Error != nil && hop == len(page.Redirects)-1 {
+			hopErr = sql.NullString{String: page.Error.Error(), Valid: true}
+		}
+		_, err = tx.Exec(sqlQuery, seedId, startUrl, page.Depth, hop, redirect.URL, redirect.Status, hopErr)
+		if err != nil {
+			return fmt.Errorf("couldn't insert the redirect: \n%v", err)
+		}
+	}
+	if page.Error == nil {
+		_, err = tx.Exec(sqlQuery, seedId, startUrl, page.Depth, len(page.Redirects), page.URL, page.Status, nil)
+		if err != nil {
+			return fmt.Errorf("couldn't insert the redirect: \n%v", err)
+		}
+	}
+	return tx.Commit()
+}
+
+// RedirectChains returns every redirect chain found on the crawl of url keyed
+// by the url first requested, the last entry is where the chain ended.
+func (s *Store) RedirectChains(url string) (map[string][]crawl.Redirect, error) {
+	sqlQuery := `
+	SELECT r.start_url, r.url, r.status FROM redirects r
+	JOIN webs_crawled w ON w.id = r.web_crawled_id
+	WHERE w.url = ? ORDER BY r.start_url, r.hop;
+	`
+	rows, err := s.db.Query(sqlQuery, url)
+	if err != nil {
+		return nil, fmt.Errorf("consult of redirects in db query failed: %w", err)
+	}
+	defer rows.Close()
+	chains := make(map[string][]crawl.Redirect)
+	for rows.Next() {
+		var start string
+		var redirect crawl.Redirect
+		if err := rows.Scan(&start, &redirect.URL, &redirect.Status); err != nil {
+			return nil, err
+		}
+		chains[start] = append(chains[start], redirect)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return chains, nil
+}
+
+// BrokenRedirects returns the chains of the crawl of url that never reached a
+// page or ended on an error status, with the reason.
+func (s *Store) BrokenRedirects(url string) (map[string]string, error) {
+	sqlQuery := `
+	SELECT r.start_url, r.url, r.status, r.error FROM redirects r
+	JOIN webs_crawled w ON w.id = r.web_crawled_id
+	WHERE w.url = ? AND (r.error IS NOT NULL OR (r.status >= 400 AND r.hop > 0))
+	ORDER BY r.start_url;
+	`
+	rows, err := s.db.Query(sqlQuery, url)
+	if err != nil {
+		return nil, fmt.Errorf("consult of broken redirects in db query failed: %w", err)
+	}
+	defer rows.Close()
+	broken := make(map[string]string)
+	for rows.Next() {
+		var start, target string
+		var status int
+		var reason sql.NullString
+		if err := rows.Scan(&start, &target, &status, &reason); err != nil {
+			return nil, err
+		}
+		if reason.Valid {
+			broken[start] = reason.String
+		} else {
+			broken[start] = fmt.Sprintf("%s answered %d", target, status)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return broken, nil
+}
+
 func (s *Store) IsUrlOnDb(url string) (*crawl.Crawler, error) {
 	var id, status int
 	var timeCrawled time.Time
-	depth := 0
 	sqlQuery := "SELECT DISTINCT id, status, last_crawled FROM webs_crawled WHERE url = ?;"
 	err := s.db.QueryRow(sqlQuery, url).Scan(&id, &status, &timeCrawled)
+	if errors.Is(err, sql.ErrNoRows) {
+		// a seed that redirected is saved under the url it ended on
+		sqlQuery = `
+		SELECT w.id, w.url, w.status, w.last_crawled FROM redirects r
+		JOIN webs_crawled w ON w.id = r.web_crawled_id
+		WHERE r.start_url = ? AND r.depth = 0 AND r.hop = 0;
+		`
+		err = s.db.QueryRow(sqlQuery, url).Scan(&id, &url, &status, &timeCrawled)
+	}
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, fmt.Errorf("didn't find the url in the db: \n%w", err)
 	} else if err != nil {
 		return nil, fmt.Errorf("consult of url in db query failed: %w", err)
 	}
-	crawler := crawl.New(url, depth, status, timeCrawled)
-	sqlQuery = "SELECT DISTINCT url_text, url FROM child_webs WHERE web_crawled_id = ?;"
-	rows, err := s.db.Query(sqlQuery, id)
+	crawler := crawl.New(url)
+	crawler.Status = status
+	crawler.LastTimeCrawled = timeCrawled
+	crawler.Page, err = s.pageInfo(id, url)
+	if err != nil {
+		return nil, err
+	}
+	sqlQuery = "SELECT COALESCE(source_url, ?), url_text, url, COALESCE(rel, ''), COALESCE(depth, 0), COALESCE(position, 0) FROM child_webs WHERE web_crawled_id = ? ORDER BY id;"
+	rows, err := s.db.Query(sqlQuery, url, id)
 	if err != nil {
 		return nil, fmt.Errorf("consult of child urls in db query failed: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var urlText, urlLink string
-		if err := rows.Scan(&urlText, &urlLink); err != nil {
+		var source, urlText, urlLink, rel string
+		var linkDepth, position int
+		if err := rows.Scan(&source, &urlText, &urlLink, &rel, &linkDepth, &position); err != nil {
 			return crawler, err
 		}
-		crawler.TextLinksCrawled[urlText] = urlLink
+		crawler.TextLinksCrawled = append(crawler.TextLinksCrawled, crawl.Link{
+			Source:   source,
+			Target:   urlLink,
+			Text:     urlText,
+			Rel:      strings.Fields(rel),
+			Depth:    linkDepth,
+			Position: position,
+		})
 	}
 	if err = rows.Err(); err != nil {
 		return nil, err
@@ -120,6 +786,33 @@ func (s *Store) IsUrlOnDb(url string) (*crawl.Crawler, error) {
 	return crawler, nil
 }
 
+// LinkGraph returns every page reached from the crawl of url with the links
+// found on it, keyed by the parent page.
+func (s *Store) LinkGraph(url string) (map[string][]string, error) {
+	sqlQuery := `
+	SELECT COALESCE(c.source_url, w.url), c.url FROM child_webs c
+	JOIN webs_crawled w ON w.id = c.web_crawled_id
+	WHERE w.url = ? ORDER BY c.id;
+	`
+	rows, err := s.db.Query(sqlQuery, url)
+	if err != nil {
+		return nil, fmt.Errorf("consult of the link graph in db query failed: %w", err)
+	}
+	defer rows.Close()
+	graph := make(map[string][]string)
+	for rows.Next() {
+		var source, target string
+		if err := rows.Scan(&source, &target); err != nil {
+			return nil, err
+		}
+		graph[source] = append(graph[source], target)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return graph, nil
+}
+
 func (s *Store) UpdateLastCrawled(url string) error {
 	sqlQuery := "UPDATE webs_crawled set last_crawled = ? WHERE url = ?;"
 	_, err := s.db.Exec(sqlQuery, time.Now(), url)
@@ -144,29 +837,45 @@ func (s *Store) FilterOldChilds(crawler *crawl.Crawler) error {
 		return fmt.Errorf("consult of child urls in db query failed: %w", err)
 	}
 	defer rows.Close()
+	saved := make(map[[2]string]bool)
 	for rows.Next() {
 		var urlText, urlLink string
 		if err := rows.Scan(&urlText, &urlLink); err != nil {
 			return err
 		}
-		if val, ok := crawler.TextLinksCrawled[urlText]; ok && val == urlLink {
-			cont = cont + 1
-			delete(crawler.TextLinksCrawled, urlText)
-		}
+		saved[[2]string{urlText, urlLink}] = true
 	}
 	if err = rows.Err(); err != nil {
 		return err
 	}
+	newLinks := crawler.TextLinksCrawled[:0]
+	for _, link := range crawler.TextLinksCrawled {
+		if saved[[2]string{link.Text, link.Target}] {
+			cont = cont + 1
+			continue
+		}
+		newLinks = append(newLinks, link)
+	}
+	crawler.TextLinksCrawled = newLinks
 	fmt.Printf("Deleted %d already saved links", cont)
 	return nil
 }
 
+// SearchTerm returns the pages whose title or text has the term first, then
+// the links whose text starts like it.
 func (s *Store) SearchTerm(searchTerm string) ([]string, error) {
 	cutSearchTerm := firstN(searchTerm, 3)
 	var urlsFound []string
-	sqlQuery := "SELECT url FROM child_webs WHERE url_text LIKE ?;"
+	sqlQuery := `
+	SELECT url FROM (
+		SELECT url, 0 AS rank FROM pages WHERE title LIKE ? OR body_text LIKE ?
+		UNION ALL
+		SELECT url, 1 AS rank FROM child_webs WHERE url_text LIKE ?
+	) GROUP BY url ORDER BY MIN(rank);
+	`
+	pageSearchTerm := fmt.Sprintf("%%%s%%", searchTerm)
 	newSearchTerm := fmt.Sprintf("%%%s%%", cutSearchTerm)
-	rows, err := s.db.Query(sqlQuery, newSearchTerm)
+	rows, err := s.db.Query(sqlQuery, pageSearchTerm, pageSearchTerm, newSearchTerm)
 	if err != nil {
 		return nil, fmt.Errorf("there was an error trying to search that term: %v ", err)
 	}
@@ -1,9 +1,12 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
@@ -18,17 +21,24 @@ func setupConTestStore(t *testing.T) *Store {
 	return &Store{db: db}
 }
 
+func newCrawler(url string, status int, timeCrawled time.Time) *crawl.Crawler {
+	crawler := crawl.New(url)
+	crawler.Status = status
+	crawler.LastTimeCrawled = timeCrawled
+	return crawler
+}
+
 func insertDataInDb(t *testing.T, s *Store) {
 	t.Helper()
 	url := "www.google.com"
-	child_webs := map[string]string{
-		"images": "www.google.com/images",
-		"duck":   "www.duckduckgo.com",
+	child_webs := []crawl.Link{
+		{Source: "www.google.com", Target: "www.google.com/images", Text: "images", Depth: 1, Position: 0},
+		{Source: "www.google.com", Target: "www.duckduckgo.com", Text: "duck", Depth: 1, Position: 1},
 	}
 	urlWithoutChildren := "www.yahoo.com"
 	status := 200
-	crawler := crawl.New(url, 0, status, time.Now())
-	crawlerWithNoChilds := crawl.New(urlWithoutChildren, 0, status, time.Now())
+	crawler := newCrawler(url, status, time.Now())
+	crawlerWithNoChilds := newCrawler(urlWithoutChildren, status, time.Now())
 	crawler.TextLinksCrawled = child_webs
 	err := s.EnterNewUrl(*crawler)
 	if err != nil {
@@ -69,7 +79,7 @@ func TestEnterNewUrl(t *testing.T) {
 	store.InitiateDB()
 	defer store.Close()
 	url := "www.google.com"
-	crawler := crawl.New(url, 0, 200, time.Now())
+	crawler := newCrawler(url, 200, time.Now())
 	err := store.EnterNewUrl(*crawler)
 	if err != nil {
 		t.Fatal(err)
@@ -87,11 +97,11 @@ func TestEnterNewChilds(t *testing.T) {
 	store.InitiateDB()
 	defer store.Close()
 	url := "www.google.com"
-	child_webs := map[string]string{
-		"images": "www.google.com/images",
-		"duck":   "www.duckduckgo.com",
+	child_webs := []crawl.Link{
+		{Source: "www.google.com", Target: "www.google.com/images", Text: "images", Depth: 1, Position: 0},
+		{Source: "www.google.com", Target: "www.duckduckgo.com", Text: "duck", Depth: 1, Position: 1},
 	}
-	crawler := crawl.New(url, 0, 200, time.Now())
+	crawler := newCrawler(url, 200, time.Now())
 	crawler.TextLinksCrawled = child_webs
 	err := store.EnterNewUrl(*crawler)
 	if err != nil {
@@ -104,7 +114,7 @@ func TestEnterNewChilds(t *testing.T) {
 	var id int
 	sqlQuery := "SELECT DISTINCT id FROM child_webs WHERE url = ?;"
 	for _, v := range child_webs {
-		err = store.db.QueryRow(sqlQuery, v).Scan(&id)
+		err = store.db.QueryRow(sqlQuery, v.Target).Scan(&id)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -140,7 +150,7 @@ func TestUpdateLastCrawled(t *testing.T) {
 	now := time.Now()
 	url := "www.google.com"
 	pastDate := now.Add(12 * time.Hour)
-	crawler := crawl.New(url, 0, 200, pastDate)
+	crawler := newCrawler(url, 200, pastDate)
 	err := store.EnterNewUrl(*crawler)
 	if err != nil {
 		t.Fatal(err)
@@ -164,15 +174,484 @@ func TestFilterOldChilds(t *testing.T) {
 	store.InitiateDB()
 	insertDataInDb(t, store)
 	url := "www.google.com"
-	child_webs := map[string]string{
-		"images":    "www.google.com/images",
-		"duck":      "www.duckduckgo.com",
-		"other_url": "www.google.com/map",
+	child_webs := []crawl.Link{
+		{Source: url, Target: "www.google.com/images", Text: "images"},
+		{Source: url, Target: "www.duckduckgo.com", Text: "duck"},
+		{Source: url, Target: "www.google.com/map", Text: "other_url"},
 	}
-	crawler := crawl.New(url, 0, 200, time.Now())
+	crawler := newCrawler(url, 200, time.Now())
 	crawler.TextLinksCrawled = child_webs
 	err := store.FilterOldChilds(crawler)
 	if err != nil {
 		t.Fatal(err)
 	}
+	if len(crawler.TextLinksCrawled) != 1 || crawler.TextLinksCrawled[0].Target != "www.google.com/map" {
+		t.Fatalf("expected only the new link to remain, got %v", crawler.TextLinksCrawled)
+	}
+}
+
+func TestEnterNewChildsKeepsDuplicateTexts(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	url := "www.google.com"
+	crawler := newCrawler(url, 200, time.Now())
+	crawler.TextLinksCrawled = []crawl.Link{
+		{Source: url, Target: "www.google.com/a", Text: "Read more", Rel: []string{"nofollow"}, Depth: 1, Position: 0},
+		{Source: url, Target: "www.google.com/b", Text: "Read more", Depth: 1, Position: 1},
+	}
+	err := store.EnterNewUrl(*crawler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.EnterNewChilds(*crawler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crawlerInDb, err := store.IsUrlOnDb(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crawlerInDb.TextLinksCrawled) != 2 {
+		t.Fatalf("expected both links with the same text to be stored, got %v", crawlerInDb.TextLinksCrawled)
+	}
+	if rel := crawlerInDb.TextLinksCrawled[0].Rel; len(rel) != 1 || rel[0] != "nofollow" {
+		t.Fatalf("expected the rel attribute to be stored, got %v", rel)
+	}
+}
+
+func TestInitiateDBMigratesOldSchema(t *testing.T) {
+	store := setupConTestStore(t)
+	defer store.Close()
+	_, err := store.db.Exec("CREATE TABLE child_webs (id INTEGER NOT NULL PRIMARY KEY, web_crawled_id INTEGER NOT NULL, url_text TEXT, url TEXT);")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.InitiateDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.db.Exec("SELECT rel, depth, position FROM child_webs;")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLinkGraph(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	url := "https://example.com/"
+	crawler := newCrawler(url, 200, time.Now())
+	crawler.TextLinksCrawled = []crawl.Link{
+		{Source: url, Target: "https://example.com/docs", Text: "docs", Depth: 1},
+		{Source: "https://example.com/docs", Target: "https://example.com/docs/intro", Text: "intro", Depth: 2},
+		{Target: "https://example.com/blog", Text: "blog", Depth: 1},
+	}
+	err := store.EnterNewUrl(*crawler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.EnterNewChilds(*crawler)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph, err := store.LinkGraph(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph[url]) != 2 {
+		t.Fatalf("expected the seed to have two children, got %v", graph[url])
+	}
+	if children := graph["https://example.com/docs"]; len(children) != 1 || children[0] != "https://example.com/docs/intro" {
+		t.Fatalf("expected the docs page to link to intro, got %v", children)
+	}
+}
+
+func TestSaveStream(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	results := []crawl.Result{
+		{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(), InfoCrawled: []crawl.Link{
+			{Source: seed, Target: "https://example.com/a", Text: "a", Depth: 1},
+		}},
+		{URL: "https://example.com/broken", Depth: 1, Error: errors.New("not found")},
+		{URL: "https://example.com/a", Depth: 1, Status: 200, FetchedAt: time.Now(), InfoCrawled: []crawl.Link{
+			{Source: "https://example.com/a", Target: "https://example.com/b", Text: "b", Depth: 2},
+		}},
+	}
+	for range 2 {
+		pages := make(chan crawl.Result)
+		go func() {
+			defer close(pages)
+			for _, result := range results {
+				pages <- result
+			}
+		}()
+		saved, err := store.SaveStream(pages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if saved != 2 {
+			t.Fatalf("expected 2 pages to be saved, got %d", saved)
+		}
+	}
+	crawler, err := store.IsUrlOnDb(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crawler.TextLinksCrawled) != 2 {
+		t.Fatalf("expected a recrawl not to duplicate links, got %v", crawler.TextLinksCrawled)
+	}
+}
+
+func TestSaveStreamRedirects(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	final := "https://www.example.com/"
+	results := []crawl.Result{
+		{URL: final, Depth: 0, Status: 200, FetchedAt: time.Now(), Redirects: []crawl.Redirect{{URL: seed, Status: 301}}},
+		{URL: "https://example.com/old", Depth: 1, Status: 404, FetchedAt: time.Now(), Redirects: []crawl.Redirect{{URL: "https://example.com/old", Status: 302}}},
+		{URL: "https://example.com/loop", Depth: 1, Status: 301, Error: crawl.ErrRedirectLoop, Redirects: []crawl.Redirect{
+			{URL: "https://example.com/loop", Status: 301}, {URL: "https://example.com/back", Status: 301},
+		}},
+	}
+	pages := make(chan crawl.Result)
+	go func() {
+		defer close(pages)
+		for _, result := range results {
+			pages <- result
+		}
+	}()
+	if _, err := store.SaveStream(pages); err != nil {
+		t.Fatal(err)
+	}
+	chains, err := store.RedirectChains(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chain := chains[seed]; len(chain) != 2 || chain[1].URL != final || chain[1].Status != 200 {
+		t.Fatalf("expected the seed chain to end on the final url, got %v", chain)
+	}
+	if len(chains["https://example.com/loop"]) != 2 {
+		t.Fatalf("expected the broken chain to be saved, got %v", chains)
+	}
+	broken, err := store.BrokenRedirects(final)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(broken) != 2 || broken["https://example.com/old"] == "" || broken["https://example.com/loop"] == "" {
+		t.Fatalf("expected the 404 and the loop to be broken redirects, got %v", broken)
+	}
+	crawler, err := store.IsUrlOnDb(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crawler.URL != final {
+		t.Fatalf("expected the redirected seed to be found by its original url, got %s", crawler.URL)
+	}
+}
+
+func TestSaveStreamFetchErrors(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	crawls := [][]crawl.Result{
+		{
+			{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(), Category: crawl.CategorySuccess},
+			{URL: "https://example.com/missing", Depth: 1, Status: 404, FetchedAt: time.Now(), Category: crawl.CategoryHTTP},
+			{URL: "https://example.com/down", Depth: 1, Error: errors.New("i/o timeout"), Category: crawl.CategoryNetwork},
+			{URL: "https://other.com/", Depth: 1, Error: errors.New("out of scope"), Category: crawl.CategoryScope},
+		},
+		{
+			{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(), Category: crawl.CategorySuccess},
+			{URL: "https://example.com/missing", Depth: 1, Status: 404, FetchedAt: time.Now(), Category: crawl.CategoryHTTP},
+			{URL: "https://example.com/down", Depth: 1, Status: 200, FetchedAt: time.Now(), Category: crawl.CategorySuccess},
+		},
+	}
+	for _, results := range crawls {
+		pages := make(chan crawl.Result)
+		go func() {
+			defer close(pages)
+			for _, result := range results {
+				pages <- result
+			}
+		}()
+		if _, err := store.SaveStream(pages); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fetchErrors, err := store.FetchErrors(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetchErrors) != 2 {
+		t.Fatalf("expected a recrawl to replace the outcome of every url, got %v", fetchErrors)
+	}
+	categories := map[string]crawl.ErrorCategory{}
+	for _, fetchErr := range fetchErrors {
+		categories[fetchErr.URL] = fetchErr.Category
+	}
+	if categories["https://example.com/missing"] != crawl.CategoryHTTP || categories["https://other.com/"] != crawl.CategoryScope {
+		t.Fatalf("expected the categories to be kept, got %v", categories)
+	}
+
+	pages := make(chan crawl.Result, 1)
+	pages <- crawl.Result{URL: "https://down.com/", Depth: 0, Error: errors.New("connection refused"), Category: crawl.CategoryNetwork}
+	close(pages)
+	if _, err := store.SaveStream(pages); err != nil {
+		t.Fatal(err)
+	}
+	fetchErrors, err = store.FetchErrors("https://down.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fetchErrors) != 1 || fetchErrors[0].Err == nil {
+		t.Fatalf("expected a failed seed to be recorded, got %v", fetchErrors)
+	}
+	pages = make(chan crawl.Result, 2)
+	pages <- crawl.Result{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(), Category: crawl.CategorySuccess}
+	pages <- crawl.Result{URL: "https://example.com/missing", Depth: 1, Error: fmt.Errorf("%w: %w", crawl.ErrCanceled, context.Canceled), Category: crawl.CategoryCanceled}
+	close(pages)
+	if _, err := store.SaveStream(pages); err != nil {
+		t.Fatal(err)
+	}
+	fetchErrors, err = store.FetchErrors(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fetchErr := range fetchErrors {
+		if fetchErr.URL == "https://example.com/missing" && fetchErr.Category != crawl.CategoryHTTP {
+			t.Fatalf("expected an interrupted fetch to keep the saved outcome, got %v", fetchErr)
+		}
+	}
+}
+
+func TestSaveStreamNotModified(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	firstCrawl := time.Now().Add(-48 * time.Hour)
+	recrawl := time.Now()
+	crawls := [][]crawl.Result{
+		{{URL: seed, Depth: 0, Status: 200, FetchedAt: firstCrawl, Validators: crawl.CacheValidators{ETag: `"v1"`}, InfoCrawled: []crawl.Link{
+			{Source: seed, Target: "https://example.com/a", Text: "a", Depth: 1},
+		}}},
+		{{URL: seed, Depth: 0, Status: 304, FetchedAt: recrawl, NotModified: true}},
+	}
+	for i, results := range crawls {
+		pages := make(chan crawl.Result)
+		go func() {
+			defer close(pages)
+			for _, result := range results {
+				pages <- result
+			}
+		}()
+		if _, err := store.SaveStream(pages); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			if validators, ok := store.Validators(seed); !ok || validators.ETag != `"v1"` {
+				t.Fatalf("expected the etag to be saved, got %v", validators)
+			}
+		}
+	}
+	crawler, err := store.IsUrlOnDb(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if crawler.Status != 200 || !crawler.LastTimeCrawled.Equal(recrawl) || len(crawler.TextLinksCrawled) != 1 {
+		t.Fatalf("expected an unchanged page to only update its last crawl, got %v", crawler)
+	}
+	if links, directives, ok := store.Links(seed); !ok || len(links) != 1 || links[0].Target != "https://example.com/a" || directives.NoFollow {
+		t.Fatalf("expected the links of the unchanged page to be kept for the next recrawl, got %v", links)
+	}
+	if validators, ok := store.Validators(seed); !ok || validators.ETag != `"v1"` {
+		t.Fatalf("expected the etag to be kept, got %v", validators)
+	}
+	if _, ok := store.Validators("https://example.com/unknown"); ok {
+		t.Fatal("expected no validators for an unknown url")
+	}
+
+	pages := make(chan crawl.Result, 1)
+	pages <- crawl.Result{URL: seed, Depth: 0, Status: 200, FetchedAt: recrawl, Validators: crawl.CacheValidators{ETag: `"v2"`},
+		Robots: crawl.RobotsDirectives{NoFollow: true}}
+	close(pages)
+	if _, err := store.SaveStream(pages); err != nil {
+		t.Fatal(err)
+	}
+	if _, directives, _ := store.Links(seed); !directives.NoFollow {
+		t.Fatal("expected the nofollow state of the page to be kept for the next recrawl")
+	}
+}
+
+func TestSaveStreamAssets(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	pdf := "https://example.com/file.pdf"
+	results := []crawl.Result{
+		{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now()},
+		{URL: pdf, Depth: 1, Status: 200, FetchedAt: time.Now(), Asset: &crawl.Asset{URL: pdf, Kind: crawl.AssetDocument, ContentType: "application/pdf", Size: 1024, Status: 200}},
+	}
+	for range 2 {
+		pages := make(chan crawl.Result)
+		go func() {
+			defer close(pages)
+			for _, result := range results {
+				pages <- result
+			}
+		}()
+		if _, err := store.SaveStream(pages); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assets, err := store.Assets(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 1 || assets[0].URL != pdf || assets[0].Kind != crawl.AssetDocument || assets[0].Size != 1024 {
+		t.Fatalf("expected the pdf to be saved once as a document, got %v", assets)
+	}
+}
+
+func TestSaveStreamPageAssets(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	first := crawl.PageInfo{Assets: []crawl.Asset{
+		{Source: seed, URL: "https://example.com/site.css", Kind: crawl.AssetStylesheet, ContentType: "text/css", Status: 200},
+		{Source: seed, URL: "https://example.com/old.png", Kind: crawl.AssetImage, Status: 404},
+	}}
+	second := crawl.PageInfo{Assets: []crawl.Asset{
+		first.Assets[0],
+		{Source: seed, URL: "http://example.com/plain.png", Kind: crawl.AssetImage, Unchecked: "scheme not allowed"},
+	}}
+	for _, info := range []crawl.PageInfo{first, second} {
+		pages := make(chan crawl.Result, 1)
+		pages <- crawl.Result{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(), Page: info}
+		close(pages)
+		if _, err := store.SaveStream(pages); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assets, err := store.Assets(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(assets) != 2 || !slices.Contains(assets, second.Assets[0]) || !slices.Contains(assets, second.Assets[1]) {
+		t.Fatalf("expected only the assets of the last crawl of the page, got %v", assets)
+	}
+}
+
+func TestSaveStreamPageInfo(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	info := crawl.PageInfo{
+		Title:       "Example",
+		Description: "An example page",
+		Lang:        "en",
+		Canonical:   seed,
+		Headings:    []crawl.Heading{{Level: 1, Text: "Welcome"}, {Level: 2, Text: "About"}},
+		WordCount:   42,
+	}
+	for range 2 {
+		pages := make(chan crawl.Result, 1)
+		pages <- crawl.Result{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(), Page: info}
+		close(pages)
+		if _, err := store.SaveStream(pages); err != nil {
+			t.Fatal(err)
+		}
+	}
+	crawler, err := store.IsUrlOnDb(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := crawler.Page
+	if saved.Title != info.Title || saved.Description != info.Description || saved.Lang != "en" || saved.WordCount != 42 {
+		t.Fatalf("expected the page record to be loaded, got %+v", saved)
+	}
+	if len(saved.Headings) != 2 || saved.Headings[1] != info.Headings[1] {
+		t.Fatalf("expected a recrawl to replace the headings, got %v", saved.Headings)
+	}
+	if !strings.Contains(crawler.String(), "title: Example") {
+		t.Fatalf("expected the title to be shown, got %s", crawler.String())
+	}
+}
+
+func TestSearchTermPageText(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	pages := make(chan crawl.Result, 2)
+	pages <- crawl.Result{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(),
+		Page:        crawl.PageInfo{Title: "Example", Text: "A readable paragraph about gophers."},
+		InfoCrawled: []crawl.Link{{Source: seed, Target: "https://example.com/facts", Text: "Gopher facts", Depth: 1}},
+	}
+	pages <- crawl.Result{URL: "https://example.com/other", Depth: 1, Status: 200, FetchedAt: time.Now(), Page: crawl.PageInfo{Text: "Nothing to see"}}
+	close(pages)
+	if _, err := store.SaveStream(pages); err != nil {
+		t.Fatal(err)
+	}
+	results, err := store.SearchTerm("gophers")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || results[0] != seed || results[1] != "https://example.com/facts" {
+		t.Fatalf("expected the page with the word first and then the link, got %v", results)
+	}
+}
+
+func TestSaveStreamNoIndex(t *testing.T) {
+	store := setupConTestStore(t)
+	store.InitiateDB()
+	defer store.Close()
+	seed := "https://example.com/"
+	hidden := "https://example.com/hidden"
+	crawls := []crawl.RobotsDirectives{{}, {NoIndex: true}}
+	for _, robots := range crawls {
+		results := []crawl.Result{
+			{URL: seed, Depth: 0, Status: 200, FetchedAt: time.Now(), InfoCrawled: []crawl.Link{
+				{Source: seed, Target: hidden, Text: "hidden", Depth: 1},
+			}},
+			{URL: hidden, Depth: 1, Status: 200, FetchedAt: time.Now(), Robots: robots, Page: crawl.PageInfo{Title: "Hidden"}, InfoCrawled: []crawl.Link{
+				{Source: hidden, Target: "https://example.com/secret", Text: "secret", Depth: 2},
+			}},
+		}
+		pages := make(chan crawl.Result)
+		go func() {
+			defer close(pages)
+			for _, result := range results {
+				pages <- result
+			}
+		}()
+		if _, err := store.SaveStream(pages); err != nil {
+			t.Fatal(err)
+		}
+	}
+	graph, err := store.LinkGraph(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(graph[seed]) != 1 || len(graph[hidden]) != 0 {
+		t.Fatalf("expected the links of the noindex page to be removed, got %v", graph)
+	}
+	var pagesSaved int
+	if err := store.db.QueryRow("SELECT COUNT(*) FROM pages WHERE url = ?;", hidden).Scan(&pagesSaved); err != nil {
+		t.Fatal(err)
+	}
+	if pagesSaved != 0 {
+		t.Fatal("expected the noindex page record to be removed")
+	}
 }
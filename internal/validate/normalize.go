@@ -0,0 +1,200 @@
+package validate
+
+import (
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// DefaultTrackingParams returns the query parameters removed by default, an
+// entry ending in "*" removes every parameter starting with it.
+func DefaultTrackingParams() []string {
+	return []string{"utm_*", "gclid", "dclid", "fbclid", "msclkid", "yclid", "mc_cid", "mc_eid", "_ga", "_hsenc", "_hsmi"}
+}
+
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+func Normalize(rawUrl string, trackingParams []string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawUrl))
+	if err != nil {
+		return "", err
+	}
+	normalized, err := NormalizeURL(u, trackingParams)
+	if err != nil {
+		return "", err
+	}
+	return normalized.String(), nil
+}
+
+// NormalizeURL returns the canonical form of u so two urls pointing to the
+// same page compare equal, u itself is left untouched. The query parameters
+// matching trackingParams are removed.
+func NormalizeURL(u *url.URL, trackingParams []string) (*url.URL, error) {
+	res := *u
+	res.Scheme = strings.ToLower(res.Scheme)
+	res.Fragment = ""
+	res.RawFragment = ""
+	if res.Host != "" {
+		host, err := normalizeHost(res.Hostname())
+		if err != nil {
+			return nil, err
+		}
+		port := res.Port()
+		if port == defaultPorts[res.Scheme] {
+			port = ""
+		}
+		if port != "" {
+			res.Host = net.JoinHostPort(host, port)
+		} else if strings.Contains(host, ":") {
+			res.Host = "[" + host + "]"
+		} else {
+			res.Host = host
+		}
+	}
+	if res.Opaque == "" {
+		escapedPath := removeDotSegments(normalizeEscapes(res.EscapedPath()))
+		if escapedPath == "" && res.Host != "" {
+			escapedPath = "/"
+		}
+		if len(escapedPath) > 1 && strings.HasSuffix(escapedPath, "/") {
+			escapedPath = strings.TrimSuffix(escapedPath, "/")
+		}
+		unescapedPath, err := url.PathUnescape(escapedPath)
+		if err != nil {
+			return nil, err
+		}
+		res.Path = unescapedPath
+		res.RawPath = escapedPath
+	}
+	res.RawQuery = normalizeQuery(res.RawQuery, trackingParams)
+	res.ForceQuery = false
+	return &res, nil
+}
+
+func normalizeHost(host string) (string, error) {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	return idna.Lookup.ToASCII(host)
+}
+
+func isTrackingParam(key string, trackingParams []string) bool {
+	key = strings.ToLower(key)
+	for _, param := range trackingParams {
+		if prefix, ok := strings.CutSuffix(param, "*"); ok {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		} else if key == param {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeQuery drops tracking parameters and sorts the rest by key and
+// value, keeping the original encoding of each pair.
+func normalizeQuery(rawQuery string, trackingParams []string) string {
+	if rawQuery == "" {
+		return ""
+	}
+	var pairs []string
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		key, _, _ := strings.Cut(pair, "=")
+		if unescapedKey, err := url.QueryUnescape(key); err == nil && isTrackingParam(unescapedKey, trackingParams) {
+			continue
+		}
+		pairs = append(pairs, normalizeEscapes(pair))
+	}
+	sort.SliceStable(pairs, func(i, j int) bool {
+		keyI, valueI, _ := strings.Cut(pairs[i], "=")
+		keyJ, valueJ, _ := strings.Cut(pairs[j], "=")
+		if keyI != keyJ {
+			return keyI < keyJ
+		}
+		return valueI < valueJ
+	})
+	return strings.Join(pairs, "&")
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters and
+// uppercases the hex digits of every other escape.
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			high, okHigh := unhex(s[i+1])
+			low, okLow := unhex(s[i+2])
+			if okHigh && okLow {
+				decoded := high<<4 | low
+				if isUnreserved(decoded) {
+					b.WriteByte(decoded)
+				} else {
+					b.WriteString("%" + strings.ToUpper(s[i+1:i+3]))
+				}
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// removeDotSegments resolves "." and ".." segments like RFC 3986 does.
+func removeDotSegments(p string) string {
+	if !strings.Contains(p, ".") {
+		return p
+	}
+	segments := strings.Split(p, "/")
+	var out []string
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				out = append(out, "")
+			}
+		case "..":
+			if len(out) > 0 && !(len(out) == 1 && out[0] == "") {
+				out = out[:len(out)-1]
+			}
+			if last {
+				out = append(out, "")
+			}
+		default:
+			out = append(out, segment)
+		}
+	}
+	return strings.Join(out, "/")
+}
@@ -0,0 +1,65 @@
+package validate
+
+import (
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	testCases := []struct {
+		name   string
+		input  string
+		expect string
+	}{
+		{name: "Lowercase scheme and host", input: "HTTPS://Example.COM/Path", expect: "https://example.com/Path"},
+		{name: "Drop fragment", input: "https://a.com/x#top", expect: "https://a.com/x"},
+		{name: "Drop default port", input: "https://A.com:443/x", expect: "https://a.com/x"},
+		{name: "Keep other ports", input: "https://a.com:8443/x", expect: "https://a.com:8443/x"},
+		{name: "Trailing slash", input: "https://a.com/x/", expect: "https://a.com/x"},
+		{name: "Empty path", input: "https://a.com", expect: "https://a.com/"},
+		{name: "Dot segments", input: "https://a.com/a/./b/../c", expect: "https://a.com/a/c"},
+		{name: "Sort query params", input: "https://a.com/?b=2&a=1&a=0", expect: "https://a.com/?a=0&a=1&b=2"},
+		{name: "Strip tracking params", input: "https://a.com/x?utm_source=news&id=3&fbclid=abc", expect: "https://a.com/x?id=3"},
+		{name: "Only tracking params", input: "https://a.com/x?utm_campaign=y", expect: "https://a.com/x"},
+		{name: "Punycode host", input: "https://bücher.example/", expect: "https://xn--bcher-kva.example/"},
+		{name: "Decode unreserved escapes", input: "https://a.com/%7Euser/%41", expect: "https://a.com/~user/A"},
+		{name: "Uppercase reserved escapes", input: "https://a.com/a%2fb?q=%3a", expect: "https://a.com/a%2Fb?q=%3A"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Normalize(tc.input, DefaultTrackingParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.expect {
+				t.Errorf("Expected %s, got %s", tc.expect, got)
+			}
+		})
+	}
+}
+
+func TestNormalizeTrackingParams(t *testing.T) {
+	got, err := Normalize("https://a.com/x?utm_source=news&ref=home&id=3", []string{"ref"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "https://a.com/x?id=3&utm_source=news" {
+		t.Errorf("Expected only the given params to be stripped, got %s", got)
+	}
+	got, _ = Normalize("https://a.com/x?utm_source=news", nil)
+	if got != "https://a.com/x?utm_source=news" {
+		t.Errorf("Expected no params to be stripped, got %s", got)
+	}
+}
+
+func TestValidateAndParseUrlKeepsTheUrl(t *testing.T) {
+	res, err := ValidateAndParseUrl("https://A.com:443/x/?utm_source=y#top")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.String() != "https://a.com/x/?utm_source=y" {
+		t.Errorf("Expected only the host of the validated url to change, got %s", res)
+	}
+	if _, err := ValidateAndParseUrl("http://a.com/"); err == nil {
+		t.Error("Expected a non https url to be rejected")
+	}
+}
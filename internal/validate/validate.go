@@ -1,21 +1,74 @@
 package validate
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"strings"
 )
 
+type SchemePolicy int
+
+const (
+	HTTPSOnly SchemePolicy = iota
+	AllowHTTP
+	UpgradeHTTP
+)
+
+var ErrSchemeNotAllowed = errors.New("scheme not allowed")
+
+func ParseSchemePolicy(policy string) (SchemePolicy, error) {
+	switch strings.ToLower(policy) {
+	case "", "https":
+		return HTTPSOnly, nil
+	case "http":
+		return AllowHTTP, nil
+	case "upgrade":
+		return UpgradeHTTP, nil
+	}
+	return HTTPSOnly, fmt.Errorf("unknown scheme policy %q, use https, http or upgrade", policy)
+}
+
+func (p SchemePolicy) String() string {
+	switch p {
+	case AllowHTTP:
+		return "http"
+	case UpgradeHTTP:
+		return "upgrade"
+	}
+	return "https"
+}
+
 func ValidateAndParseUrl(urlToValidate string) (*url.URL, error) {
+	return ValidateWithPolicy(urlToValidate, HTTPSOnly)
+}
+
+// ValidateWithPolicy parses the url without its fragment, rejecting it with
+// ErrSchemeNotAllowed when its scheme isn't allowed by the policy. With
+// UpgradeHTTP an http url is returned with the https scheme. Only the host is
+// lowercased and loses its default port, the rest of the url is not
+// normalized since servers may answer its normalized form differently.
+func ValidateWithPolicy(urlToValidate string, policy SchemePolicy) (*url.URL, error) {
+	urlToValidate, _, _ = strings.Cut(urlToValidate, "#")
 	res, err := url.ParseRequestURI(urlToValidate)
 	if err != nil {
 		return nil, err
 	}
-	if res.Scheme != "https" {
-		return nil, fmt.Errorf("The scheme of the url is not https, any other scheme is blocked")
-	}
 	res.Scheme = strings.ToLower(res.Scheme)
 	res.Host = strings.ToLower(res.Host)
+	switch {
+	case res.Scheme == "https":
+		res.Host = strings.TrimSuffix(res.Host, ":443")
+	case res.Scheme == "http" && policy == AllowHTTP:
+		res.Host = strings.TrimSuffix(res.Host, ":80")
+	case res.Scheme == "http" && policy == UpgradeHTTP:
+		res.Scheme = "https"
+		res.Host = strings.TrimSuffix(res.Host, ":80")
+	case res.Scheme == "http":
+		return nil, fmt.Errorf("%w: the scheme of the url is not https, use the http or upgrade scheme policy to allow it", ErrSchemeNotAllowed)
+	default:
+		return nil, fmt.Errorf("%w: %s urls can't be crawled", ErrSchemeNotAllowed, res.Scheme)
+	}
 	return res, nil
 }
 
@@ -0,0 +1,54 @@
+package validate
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidateWithPolicy(t *testing.T) {
+	testCases := []struct {
+		name        string
+		input       string
+		policy      SchemePolicy
+		expect      string
+		expectError error
+	}{
+		{name: "Https only accepts https", input: "https://a.com/", policy: HTTPSOnly, expect: "https://a.com/"},
+		{name: "Https only rejects http", input: "http://a.com/", policy: HTTPSOnly, expectError: ErrSchemeNotAllowed},
+		{name: "Allow http keeps http", input: "HTTP://a.com:8080/x", policy: AllowHTTP, expect: "http://a.com:8080/x"},
+		{name: "Host is lowercased without its default port", input: "https://Example.COM:443/About", policy: HTTPSOnly, expect: "https://example.com/About"},
+		{name: "Http default port is dropped", input: "http://a.com:80/x", policy: AllowHTTP, expect: "http://a.com/x"},
+		{name: "Url is not normalized", input: "https://a.com/about/?utm_source=x#top", policy: HTTPSOnly, expect: "https://a.com/about/?utm_source=x"},
+		{name: "Upgrade turns http into https", input: "http://a.com:80/x", policy: UpgradeHTTP, expect: "https://a.com/x"},
+		{name: "Other schemes are rejected", input: "ftp://a.com/", policy: AllowHTTP, expectError: ErrSchemeNotAllowed},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := ValidateWithPolicy(tc.input, tc.policy)
+			if tc.expectError != nil {
+				if !errors.Is(err, tc.expectError) {
+					t.Fatalf("Expected error %v, got %v", tc.expectError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.String() != tc.expect {
+				t.Errorf("Expected %s, got %s", tc.expect, res)
+			}
+		})
+	}
+}
+
+func TestParseSchemePolicy(t *testing.T) {
+	for _, policy := range []SchemePolicy{HTTPSOnly, AllowHTTP, UpgradeHTTP} {
+		parsed, err := ParseSchemePolicy(policy.String())
+		if err != nil || parsed != policy {
+			t.Errorf("Expected %s to parse back, got %v %v", policy, parsed, err)
+		}
+	}
+	if _, err := ParseSchemePolicy("gopher"); err == nil {
+		t.Error("Expected an unknown policy to fail")
+	}
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
+	"regexp"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/AgustinPagotto/go-webcrawler/internal/crawl"
@@ -13,22 +19,94 @@ import (
 	"github.com/AgustinPagotto/go-webcrawler/internal/validate"
 )
 
-func receiveFlags() (string, int, string) {
+func receiveFlags() (string, int, string, validate.SchemePolicy, []crawl.Option) {
 	var urlFromCli string
 	var depthCrawl int
 	var searchTerm string
+	var scope crawl.ScopeRules
+	var excludeExtensions string
+	var stripParams string
+	var schemePolicy string
+	var allowedNetworks string
+	var maxRedirects int
+	retryPolicy := crawl.DefaultRetryPolicy()
+	var maxBodySize int64
+	var headUnknown bool
+	var skipNofollow bool
+	var ignoreRobotsMeta bool
+	var checkAssets bool
 	flag.StringVar(&urlFromCli, "url", "", "Url to be Crawled")
 	flag.StringVar(&urlFromCli, "u", "", "Url to be Crawled")
 	flag.IntVar(&depthCrawl, "depth", 1, "Depth of the crawl")
 	flag.IntVar(&depthCrawl, "d", 1, "Depth of the crawl")
 	flag.StringVar(&searchTerm, "search", "", "Word to Search")
 	flag.StringVar(&searchTerm, "s", "", "Word to Search")
+	flag.StringVar(&scope.Mode, "scope", crawl.ScopeAll, "Links to follow: all, host, subdomains or domain of the seed url")
+	flag.StringVar(&scope.PathPrefix, "path-prefix", "", "Only follow links whose path is this prefix or below it")
+	flag.Func("include", "Only follow links matching this regex, can be repeated", func(pattern string) error {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		scope.Include = append(scope.Include, re)
+		return nil
+	})
+	flag.Func("exclude", "Don't follow links matching this regex, can be repeated", func(pattern string) error {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return err
+		}
+		scope.Exclude = append(scope.Exclude, re)
+		return nil
+	})
+	flag.StringVar(&excludeExtensions, "exclude-ext", "", "Comma separated file extensions not to follow, like pdf,zip")
+	flag.StringVar(&stripParams, "strip-params", strings.Join(validate.DefaultTrackingParams(), ","), "Comma separated query parameters removed from urls, a trailing * matches a prefix")
+	flag.StringVar(&schemePolicy, "scheme", "https", "Schemes crawled: https only, http to allow plain http too, or upgrade to try https first")
+	flag.StringVar(&allowedNetworks, "allow-net", "", "Comma separated private networks or ips that can be crawled, like 10.0.0.0/8")
+	flag.IntVar(&maxRedirects, "max-redirects", 10, "Redirects followed for a single url before giving up")
+	flag.IntVar(&retryPolicy.MaxAttempts, "retries", retryPolicy.MaxAttempts, "Attempts made for a page that failed with a timeout or a retryable status like 502, 1 disables retries")
+	flag.Int64Var(&maxBodySize, "max-body", 10<<20, "Most bytes of a page that are parsed, the rest is ignored")
+	flag.BoolVar(&headUnknown, "head-unknown", false, "Send a HEAD request first to urls whose extension doesn't look like a page")
+	flag.BoolVar(&skipNofollow, "skip-nofollow", false, "Don't follow links marked rel=nofollow, they are still recorded")
+	flag.BoolVar(&ignoreRobotsMeta, "ignore-robots-meta", false, "Index noindex pages and follow the links of nofollow pages, for audits")
+	flag.BoolVar(&checkAssets, "check-assets", false, "Request the images, scripts, stylesheets and media of every page to record their status")
 	flag.Parse()
-	return urlFromCli, depthCrawl, searchTerm
+	var trackingParams []string
+	if stripParams != "" {
+		trackingParams = strings.Split(stripParams, ",")
+	}
+	if !crawl.ValidScopeMode(scope.Mode) {
+		log.Fatalf("Unknown scope %q, use all, host, subdomains or domain\n", scope.Mode)
+	}
+	if excludeExtensions != "" {
+		scope.ExcludeExtensions = strings.Split(excludeExtensions, ",")
+	}
+	policy, err := validate.ParseSchemePolicy(schemePolicy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	networks, err := crawl.ParseNetworks(allowedNetworks)
+	if err != nil {
+		log.Fatalf("Invalid allowed network: %s\n", err)
+	}
+	crawlOptions := []crawl.Option{crawl.WithScopeRules(scope), crawl.WithSchemePolicy(policy), crawl.WithAllowedNetworks(networks), crawl.WithMaxRedirects(maxRedirects), crawl.WithRetryPolicy(retryPolicy), crawl.WithMaxBodySize(maxBodySize), crawl.WithTrackingParams(trackingParams)}
+	if headUnknown {
+		crawlOptions = append(crawlOptions, crawl.WithHeadUnknown())
+	}
+	if skipNofollow {
+		crawlOptions = append(crawlOptions, crawl.WithSkipNofollow())
+	}
+	if ignoreRobotsMeta {
+		crawlOptions = append(crawlOptions, crawl.WithIgnoreRobotsMeta())
+	}
+	if checkAssets {
+		crawlOptions = append(crawlOptions, crawl.WithCheckAssets())
+	}
+	return urlFromCli, depthCrawl, searchTerm, policy, crawlOptions
 }
 
 func main() {
-	urlToCrawl, depthCrawl, searchTerm := receiveFlags()
+	urlToCrawl, depthCrawl, searchTerm, policy, crawlOptions := receiveFlags()
 	searchBool, err := validate.ValidateFlags(urlToCrawl, depthCrawl, searchTerm)
 	if err != nil {
 		log.Fatal(err)
@@ -42,14 +120,32 @@ func main() {
 	if searchBool {
 		performSearch(store, searchTerm)
 	} else {
-		performCrawl(store, urlToCrawl, depthCrawl)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		performCrawl(ctx, store, urlToCrawl, depthCrawl, policy, crawlOptions)
 	}
 }
 
-func performCrawl(store *db.Store, urlToCrawl string, depthCrawl int) {
+func performCrawl(ctx context.Context, store *db.Store, urlToCrawl string, depthCrawl int, policy validate.SchemePolicy, crawlOptions []crawl.Option) {
 	var needsRecrawl bool
 	const recrawlAfter = 1 * 24 * time.Hour
-	crawler, err := store.IsUrlOnDb(urlToCrawl)
+	// pages are saved under their normalized url but requested as given
+	config := crawl.DefaultConfig()
+	for _, opt := range crawlOptions {
+		opt(&config)
+	}
+	seedUrl := urlToCrawl
+	if validUrl, err := validate.ValidateWithPolicy(urlToCrawl, policy); err == nil {
+		urlToCrawl = validUrl.String()
+		if normalizedUrl, err := validate.NormalizeURL(validUrl, config.TrackingParams); err == nil {
+			seedUrl = normalizedUrl.String()
+		}
+	}
+	crawler, err := store.IsUrlOnDb(seedUrl)
+	if errors.Is(err, sql.ErrNoRows) && seedUrl != urlToCrawl {
+		// a seed that redirected has its chain saved from the url requested
+		crawler, err = store.IsUrlOnDb(urlToCrawl)
+	}
 	if crawler == nil && !errors.Is(err, sql.ErrNoRows) {
 		log.Fatal(err)
 	} else if err != nil {
@@ -63,37 +159,51 @@ func performCrawl(store *db.Store, urlToCrawl string, depthCrawl int) {
 		}
 	}
 	if crawler == nil || needsRecrawl {
-		crawler := crawl.New(urlToCrawl, depthCrawl, 0, time.Now())
-		err := crawler.Crawl()
+		if needsRecrawl {
+			fmt.Print("updating url with recrawl")
+			crawlOptions = append(crawlOptions, crawl.WithValidators(store.Validators), crawl.WithStoredLinks(store.Links))
+		}
+		crawlOptions = append(crawlOptions, crawl.WithMaxDepth(depthCrawl), crawl.WithStreamOnly())
+		crawler := crawl.New(urlToCrawl, crawlOptions...)
+		saved, err := store.SaveStream(crawler.Stream(ctx))
 		if err != nil {
+			log.Fatalf("Error saving the crawl into db: %s\n", err)
+		}
+		if err := crawler.Err(); ctx.Err() != nil {
+			log.Printf("Crawl interrupted, saved the %d pages already crawled\n", saved)
+		} else if err != nil && saved == 0 {
 			log.Fatalf("Error crawling page: %s\n", err)
+		} else if err != nil {
+			fmt.Printf("Error crawling page childs: %s\n", err)
 		}
-		if depthCrawl > 1 {
-			err = crawler.CrawlChildrenWithDepth()
-			if err != nil {
-				fmt.Printf("Error crawling page childs: %s\n", err)
-			}
+		for skippedUrl, reason := range crawler.Skipped {
+			log.Printf("Skipped %s: %s\n", skippedUrl, reason)
 		}
-		if needsRecrawl {
-			fmt.Print("updating url with recrawl")
-			err = store.UpdateLastCrawled(crawler.URL)
-			if err != nil {
-				log.Fatalf("Error trying to update the date on the url %s\n", err)
-			}
-			err = store.FilterOldChilds(crawler)
+		broken, err := store.BrokenRedirects(crawler.URL)
+		if err != nil {
+			log.Printf("Error reading the redirects of the crawl: %s\n", err)
+		}
+		for startUrl, reason := range broken {
+			log.Printf("Broken redirect %s: %s\n", startUrl, reason)
+		}
+		if crawler.Config.CheckAssets {
+			assets, err := store.Assets(crawler.URL)
 			if err != nil {
-				log.Fatalf("Error trying to update the date on the url %s\n", err)
+				log.Printf("Error reading the assets of the crawl: %s\n", err)
 			}
-		} else {
-			err = store.EnterNewUrl(*crawler)
-			if err != nil {
-				log.Fatalf("Error inserting new url into db: %s\n", err)
+			for _, asset := range assets {
+				if asset.Source == "" {
+					continue
+				}
+				if asset.Unchecked != "" {
+					log.Printf("Unchecked %s %s on %s: %s\n", asset.Kind, asset.URL, asset.Source, asset.Unchecked)
+				} else if asset.Status == 0 || asset.Status >= 400 {
+					log.Printf("Broken %s %s on %s: status %d\n", asset.Kind, asset.URL, asset.Source, asset.Status)
+				}
 			}
 		}
-		if crawler != nil {
-			store.EnterNewChilds(*crawler)
-		}
 		log.Println("Page was crawled successfuly", crawler.String())
+		log.Println("Outcome of every url found:", crawler.Summary())
 	}
 }
 